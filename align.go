@@ -0,0 +1,111 @@
+package glfont
+
+import "fmt"
+
+// Align represents the horizontal alignment of each line of text around the x coordinate.
+type Align uint8
+
+// Known alignments.
+const (
+	AlignLeft   Align = iota // Lines start at x
+	AlignCenter              // Lines are centered on x
+	AlignRight               // Lines end at x
+)
+
+// PrintfAligned draws a string to the screen like Printf, with each line aligned around x.
+// AlignLeft is the same as Printf.
+func (f *Font) PrintfAligned(x, y float32, scale float32, align Align, fs string, argv ...interface{}) error {
+
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	if len(indices) == 0 {
+		return nil
+	}
+
+	coords := f.scratch(scale)
+	for _, line := range splitLines(indices) {
+		//vertical lines are aligned around y instead
+		if f.dir == TopToBottom {
+			coords = f.appendText(coords, x, y-f.down(f.alignOffset(scale, align, line)), scale, line)
+		} else {
+			coords = f.appendText(coords, x-f.alignOffset(scale, align, line), y, scale, line)
+		}
+		x, y = f.nextLine(x, y, scale)
+	}
+
+	return f.draw(coords)
+}
+
+// Anchor represents the point of the bounds of a piece of text placed at the x, y
+// coordinates passed to PrintfAnchored.
+type Anchor uint8
+
+// Known anchors.
+const (
+	TopLeft Anchor = iota
+	TopCenter
+	TopRight
+	CenterLeft
+	Center
+	CenterRight
+	BottomLeft
+	BottomCenter
+	BottomRight
+)
+
+// PrintfAnchored draws a string to the screen like Printf, moved so that the anchor point of
+// its bounds lands on x, y, e.g. TopLeft to draw text in a box. The bounds are the ones
+// returned by DrawString: from the ascent of the first line to the descent of the last one.
+func (f *Font) PrintfAnchored(x, y float32, scale float32, anchor Anchor, fs string, argv ...interface{}) error {
+
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	if len(indices) == 0 {
+		return nil
+	}
+
+	width, lines := f.layout(scale, indices, func(int, *character, *Font, float32, float32) {})
+	ax, ay := f.anchorPoint(f.textBounds(0, 0, scale, width, lines), anchor)
+
+	return f.draw(f.appendText(f.scratch(scale), x-ax, y-ay, scale, indices))
+}
+
+// anchorPoint returns the position of anchor in the bounds r.
+func (f *Font) anchorPoint(r Rect, anchor Anchor) (float32, float32) {
+	across := float32(anchor%3) / 2
+	down := float32(anchor/3) / 2
+	//the top of y-up text is its largest y
+	if f.yAxis == YUp {
+		down = 1 - down
+	}
+	return r.X + r.W*across, r.Y + r.H*down
+}
+
+// alignOffset returns how far left of x the origin of a single line is with the given alignment.
+func (f *Font) alignOffset(scale float32, align Align, line []rune) float32 {
+	var offset float32
+	switch align {
+	case AlignCenter:
+		offset = f.lineWidth(scale, line) / 2
+	case AlignRight:
+		offset = f.lineWidth(scale, line)
+	}
+	//right to left lines extend to the left of their origin
+	if f.dir == RightToLeft {
+		offset -= f.lineWidth(scale, line)
+	}
+	return offset
+}
+
+// splitLines splits text on newlines, without the newlines themselves.
+func splitLines(text []rune) [][]rune {
+	var lines [][]rune
+	start := 0
+	for i, r := range text {
+		if r == '\n' {
+			lines = append(lines, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(lines, text[start:])
+}
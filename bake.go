@@ -0,0 +1,232 @@
+package glfont
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"image"
+	"io"
+	"os"
+
+	"golang.org/x/image/math/fixed"
+)
+
+//bakedMagic and bakedVersion start the files written by SaveBaked, the version changing
+//with their layout
+const (
+	bakedMagic   = "GLFB"
+	bakedVersion = 1
+)
+
+//bakedKernRunes is the largest number of runes whose kerning pairs are baked, above
+//which, as with CJK ranges, baked fonts are drawn without kerning
+const bakedKernRunes = 512
+
+//bakedFont is the content of a file written by SaveBaked, after its header
+type bakedFont struct {
+	Options    Options
+	Width      int
+	Height     int
+	Pix        []byte // Coverage of the atlas, one byte per pixel.
+	Ascent     float32
+	Descent    float32
+	LineHeight float32
+	Density    float32
+	Spread     int
+	Underline  [2]float32 // Position and thickness of the underline.
+	Strike     [2]float32 // Position and thickness of the strikethrough.
+	Glyphs     []bakedGlyph
+	Tofu       bakedGlyph
+	Solid      bakedGlyph
+	Ligatures  []bakedLigature
+	Kerning    []bakedKern
+}
+
+//bakedGlyph is a character of a baked font and its rune
+type bakedGlyph struct {
+	Rune                rune
+	X, Y, Width, Height int
+	Advance, VAdvance   int
+	BearingH, BearingV  int
+}
+
+//bakedLigature is a ligature of a baked font
+type bakedLigature struct {
+	Runes []rune
+	Glyph bakedGlyph
+}
+
+//bakedKern is the kerning of a pair of runes of a baked font
+type bakedKern struct {
+	A, B rune
+	Kern fixed.Int26_6
+}
+
+func bakeGlyph(r rune, char *character) bakedGlyph {
+	return bakedGlyph{r, char.x, char.y, char.width, char.height, char.advance, char.vadvance, char.bearingH, char.bearingV}
+}
+
+func (g bakedGlyph) character() *character {
+	return &character{x: g.X, y: g.Y, width: g.Width, height: g.Height, advance: g.Advance, vadvance: g.VAdvance, bearingH: g.BearingH, bearingV: g.BearingV}
+}
+
+// SaveBaked writes the atlas of the font and the metrics of its glyphs to a file, for
+// LoadBaked to load the font again without rasterizing it. Glyphs added to a dynamic atlas
+// so far are saved with the others. It reads the texture back from the GPU, so like every
+// other GL call it must be made on the thread that owns the GL context.
+func (f *Font) SaveBaked(path string) error {
+	if f.fontChar == nil {
+		return fmt.Errorf("font is closed")
+	}
+
+	baked := bakedFont{
+		Options:    f.options,
+		Width:      int(f.atlasWidth),
+		Height:     int(f.atlasHeight),
+		Pix:        f.readAtlas().Pix,
+		Ascent:     f.ascent,
+		Descent:    f.descent,
+		LineHeight: f.lineHeight,
+		Density:    f.density,
+		Spread:     f.spread,
+		Underline:  [2]float32{f.underlinePos, f.underlineThick},
+		Strike:     [2]float32{f.strikePos, f.strikeThick},
+		Tofu:       bakeGlyph(0, f.tofu),
+		Solid:      bakeGlyph(0, f.solid),
+	}
+	//the loaded font has every glyph in its atlas
+	baked.Options.Atlas.Dynamic = false
+
+	var runes []rune
+	for r, char := range f.fontChar {
+		baked.Glyphs = append(baked.Glyphs, bakeGlyph(r, char))
+		runes = append(runes, r)
+	}
+	if f.dynamic != nil {
+		f.faceMu.Lock()
+		for r, char := range f.dynamic.glyphs {
+			if char != nil {
+				baked.Glyphs = append(baked.Glyphs, bakeGlyph(r, char))
+				runes = append(runes, r)
+			}
+		}
+		f.faceMu.Unlock()
+	}
+	for _, set := range f.ligatures {
+		for _, lig := range set {
+			baked.Ligatures = append(baked.Ligatures, bakedLigature{lig.runes, bakeGlyph(0, lig.char)})
+		}
+	}
+
+	if len(runes) <= bakedKernRunes {
+		for _, a := range runes {
+			for _, b := range runes {
+				if kern := f.kern(a, b); kern != 0 {
+					baked.Kerning = append(baked.Kerning, bakedKern{a, b, kern})
+				}
+			}
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := baked.write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+//write writes the header and the compressed content of a baked font file to w
+func (baked *bakedFont) write(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	buf.WriteString(bakedMagic)
+	binary.Write(buf, binary.LittleEndian, uint32(bakedVersion))
+	z := gzip.NewWriter(buf)
+	if err := gob.NewEncoder(z).Encode(baked); err != nil {
+		return err
+	}
+	if err := z.Close(); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+//readBaked reads a baked font file written by write from r
+func readBaked(r io.Reader) (*bakedFont, error) {
+	buf := bufio.NewReader(r)
+
+	header := make([]byte, len(bakedMagic))
+	var version uint32
+	if _, err := io.ReadFull(buf, header); err != nil || string(header) != bakedMagic {
+		return nil, fmt.Errorf("not a baked font")
+	}
+	if err := binary.Read(buf, binary.LittleEndian, &version); err != nil || version != bakedVersion {
+		return nil, fmt.Errorf("baked with version %d, not %d", version, bakedVersion)
+	}
+
+	z, err := gzip.NewReader(buf)
+	if err != nil {
+		return nil, err
+	}
+	baked := new(bakedFont)
+	if err := gob.NewDecoder(z).Decode(baked); err != nil {
+		return nil, err
+	}
+	if len(baked.Pix) != baked.Width*baked.Height {
+		return nil, fmt.Errorf("truncated atlas")
+	}
+	return baked, nil
+}
+
+// LoadBaked loads a font saved with SaveBaked, drawing it with program like LoadTrueTypeFont,
+// without reading or rasterizing the font file. It returns an error if the file was written
+// by another version of the package or baked at another scale than the given one, for the
+// caller to load the font file again. Baked fonts have no dynamic atlas, are drawn without
+// kerning when more than 512 runes were baked, and cannot be reloaded or rebuilt.
+func LoadBaked(path string, program uint32, scale int32) (*Font, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	baked, err := readBaked(file)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if baked.Options.Scale != scale {
+		return nil, fmt.Errorf("%s: baked at scale %d, not %d", path, baked.Options.Scale, scale)
+	}
+
+	f := newFont(baked.Options)
+	f.atlasWidth, f.atlasHeight = float32(baked.Width), float32(baked.Height)
+	f.ascent, f.descent, f.lineHeight = baked.Ascent, baked.Descent, baked.LineHeight
+	f.density = baked.Density
+	f.sdf, f.spread = baked.Options.Atlas.SDF, baked.Spread
+	f.underlinePos, f.underlineThick = baked.Underline[0], baked.Underline[1]
+	f.strikePos, f.strikeThick = baked.Strike[0], baked.Strike[1]
+	f.tofu, f.solid = baked.Tofu.character(), baked.Solid.character()
+	for _, g := range baked.Glyphs {
+		f.fontChar[g.Rune] = g.character()
+	}
+	for _, l := range baked.Ligatures {
+		if f.ligatures == nil {
+			f.ligatures = make(map[rune][]*ligature)
+		}
+		f.ligatures[l.Runes[0]] = append(f.ligatures[l.Runes[0]], &ligature{runes: l.Runes, char: l.Glyph.character()})
+	}
+	f.kerning = make(map[[2]rune]fixed.Int26_6)
+	for _, k := range baked.Kerning {
+		f.kerning[[2]rune{k.A, k.B}] = k.Kern
+	}
+
+	f.program = program
+	f.locations = lookupLocations(program)
+	f.createGLObjects(&image.Gray{Pix: baked.Pix, Stride: baked.Width, Rect: image.Rect(0, 0, baked.Width, baked.Height)})
+
+	return f, nil
+}
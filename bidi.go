@@ -0,0 +1,235 @@
+package glfont
+
+import (
+	"unicode"
+
+	"golang.org/x/text/unicode/bidi"
+)
+
+// SetBidi enables reordering text that mixes left to right and right to left scripts, such
+// as English words and numbers in Arabic or Hebrew text, from the logical order it is
+// stored in to the visual order it is read in, following the Unicode bidirectional
+// algorithm. Each line is reordered on its own, with the direction of the font as its base
+// direction, and the brackets of right to left runs are mirrored. Explicit embedding and
+// isolate controls are not supported. It is disabled by default, to spare the cost to text
+// in a single direction, and does not apply to vertical text.
+func (f *Font) SetBidi(enabled bool) {
+	f.mu.Lock()
+	f.useBidi = enabled
+	f.mu.Unlock()
+}
+
+//reorder returns the runes of text and their scripts in the order they are laid out in the
+//direction of the font, and the index in text of each of them
+func (f *Font) reorder(text []rune, scripts []Script) ([]rune, []Script, []int) {
+	rtl := f.dir == RightToLeft
+
+	order := make([]int, 0, len(text))
+	levels := make([]uint8, 0, len(text))
+	start := 0
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && text[i] != '\n' {
+			continue
+		}
+		line := bidiLevels(text[start:i], rtl)
+		for _, k := range visualOrder(line, rtl) {
+			order = append(order, start+k)
+		}
+		levels = append(levels, line...)
+		if i < len(text) {
+			order = append(order, i)
+			levels = append(levels, 0)
+		}
+		start = i + 1
+	}
+
+	//combining marks of the runs laid out backwards stay after the rune they are over
+	base := uint8(0)
+	if rtl {
+		base = 1
+	}
+	for k := 0; k < len(order); k++ {
+		if levels[order[k]]%2 == base || !unicode.Is(unicode.Mn, text[order[k]]) {
+			continue
+		}
+		j := k
+		for j < len(order) && unicode.Is(unicode.Mn, text[order[j]]) {
+			j++
+		}
+		if j < len(order) && levels[order[j]]%2 != base {
+			mark := order[j]
+			copy(order[k+1:j+1], order[k:j])
+			order[k] = mark
+		}
+		k = j
+	}
+
+	visual := make([]rune, len(text))
+	var visualScripts []Script
+	if scripts != nil {
+		visualScripts = make([]Script, len(text))
+	}
+	for k, i := range order {
+		visual[k] = text[i]
+		if levels[i]%2 == 1 {
+			visual[k] = mirror(text[i])
+		}
+		if scripts != nil {
+			visualScripts[k] = scripts[i]
+		}
+	}
+	return visual, visualScripts, order
+}
+
+//visualOrder returns the indices of the runes of a line with levels in the order they are
+//laid out, from left to right, or from right to left for rtl
+func visualOrder(levels []uint8, rtl bool) []int {
+	order := make([]int, len(levels))
+	var highest uint8
+	for i := range order {
+		order[i] = i
+		highest = maxLevel(highest, levels[i])
+	}
+
+	//reverse every sequence at a level or higher, from the highest level to the lowest odd one
+	for level := highest; level >= 1; level-- {
+		for i := 0; i < len(order); {
+			if levels[order[i]] < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(order) && levels[order[j]] >= level {
+				j++
+			}
+			reverseInts(order[i:j])
+			i = j
+		}
+	}
+
+	if rtl {
+		reverseInts(order)
+	}
+	return order
+}
+
+//bidiLevels returns the embedding level of each rune of a line: even for the runes read
+//from left to right and odd for the others, starting at 1 for rtl and 0 otherwise
+func bidiLevels(line []rune, rtl bool) []uint8 {
+	levels := make([]uint8, len(line))
+	if len(line) == 0 {
+		return levels
+	}
+
+	//a leading mark sets the direction of the paragraph, removed from the runs after
+	base, mark := uint8(0), '\u200e'
+	if rtl {
+		base, mark = 1, '\u200f'
+	}
+	for i := range levels {
+		levels[i] = base
+	}
+	var p bidi.Paragraph
+	if _, err := p.SetString(string(mark) + string(line)); err != nil {
+		return levels
+	}
+	o, err := p.Order()
+	if err != nil {
+		return levels
+	}
+
+	//the runs only tell the direction of the runes, numbers in a right to left run of a
+	//left to right line are one level deeper
+	var numbers []bool
+	if !rtl {
+		numbers = numberRunes(line)
+	}
+	for i := 0; i < o.NumRuns(); i++ {
+		run := o.Run(i)
+		first, last := run.Pos()
+		if first == 0 {
+			first = 1
+		}
+		for k := first - 1; k < last && k < len(line); k++ {
+			switch {
+			case run.Direction() == bidi.RightToLeft:
+				levels[k] = 1
+			case rtl || numbers[k]:
+				levels[k] = 2
+			default:
+				levels[k] = 0
+			}
+		}
+	}
+	return levels
+}
+
+//numberRunes reports which runes of a left to right line are part of numbers read from
+//left to right inside right to left text: Arabic numbers, European numbers following right
+//to left letters, and the separators and signs that belong to them
+func numberRunes(line []rune) []bool {
+	classes := make([]bidi.Class, len(line))
+	numbers := make([]bool, len(line))
+	afterRTL := false
+	for i, r := range line {
+		props, _ := bidi.LookupRune(r)
+		classes[i] = props.Class()
+		switch classes[i] {
+		case bidi.L:
+			afterRTL = false
+		case bidi.R, bidi.AL:
+			afterRTL = true
+		case bidi.AN:
+			numbers[i] = true
+		case bidi.EN:
+			numbers[i] = afterRTL
+		}
+	}
+
+	for i, class := range classes {
+		switch class {
+		case bidi.ES, bidi.CS:
+			//a single separator between two digits
+			numbers[i] = i > 0 && i+1 < len(line) && numbers[i-1] && numbers[i+1]
+		case bidi.NSM:
+			numbers[i] = i > 0 && numbers[i-1]
+		case bidi.ET:
+			//currency and percent signs next to the digits
+			for j := i - 1; j >= 0 && !numbers[i]; j-- {
+				if classes[j] != bidi.ET {
+					numbers[i] = numbers[j] && classes[j] == bidi.EN
+					break
+				}
+			}
+			for j := i + 1; j < len(line) && !numbers[i]; j++ {
+				if classes[j] != bidi.ET {
+					numbers[i] = numbers[j] && classes[j] == bidi.EN
+					break
+				}
+			}
+		}
+	}
+	return numbers
+}
+
+//mirror returns the counterpart of a bracket drawn in right to left text, like ) for (,
+//and r itself for the other runes
+func mirror(r rune) rune {
+	if props, _ := bidi.LookupRune(r); !props.IsBracket() {
+		return r
+	}
+	return []rune(bidi.ReverseString(string(r)))[0]
+}
+
+func maxLevel(a, b uint8) uint8 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func reverseInts(s []int) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
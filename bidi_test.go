@@ -0,0 +1,102 @@
+package glfont
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBidiLevels(t *testing.T) {
+	tests := []struct {
+		text   string
+		rtl    bool
+		levels []uint8
+	}{
+		{"abc", false, []uint8{0, 0, 0}},
+		{"שלום", true, []uint8{1, 1, 1, 1}},
+		//Hebrew with embedded Latin, in either base direction
+		{"abc שלום def", false, []uint8{0, 0, 0, 0, 1, 1, 1, 1, 0, 0, 0, 0}},
+		{"שלום abc עולם", true, []uint8{1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1}},
+		//digits in right to left text are read from left to right
+		{"שלום 123 עולם", true, []uint8{1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1}},
+		{"abc שלום 12.5 def", false, []uint8{0, 0, 0, 0, 1, 1, 1, 1, 1, 2, 2, 2, 2, 0, 0, 0, 0}},
+		//brackets and neutrals at run boundaries take the direction of the line
+		{"(שלום) abc", false, []uint8{0, 1, 1, 1, 1, 0, 0, 0, 0, 0}},
+		{"abc (שלום)", true, []uint8{2, 2, 2, 1, 1, 1, 1, 1, 1, 1}},
+		{"abc, שלום!", false, []uint8{0, 0, 0, 0, 0, 1, 1, 1, 1, 0}},
+		{"שלום, abc!", true, []uint8{1, 1, 1, 1, 1, 1, 2, 2, 2, 1}},
+	}
+	for _, tt := range tests {
+		if got := bidiLevels([]rune(tt.text), tt.rtl); !reflect.DeepEqual(got, tt.levels) {
+			t.Errorf("bidiLevels(%q, %v) = %v, want %v", tt.text, tt.rtl, got, tt.levels)
+		}
+	}
+}
+
+func TestNumberRunes(t *testing.T) {
+	tests := []struct {
+		text    string
+		numbers string
+	}{
+		{"abc 123", "       "},
+		{"שלום 123", "     ###"},
+		{"שלום 12.5", "     ####"},
+		{"שלום 12. abc", "     ##     "},
+		{"שלום 50%", "     ###"},
+		{"$12 שלום", "        "},
+		{"١٢٣", "###"},
+	}
+	for _, tt := range tests {
+		numbers := numberRunes([]rune(tt.text))
+		got := make([]rune, len(numbers))
+		for i, n := range numbers {
+			got[i] = ' '
+			if n {
+				got[i] = '#'
+			}
+		}
+		if string(got) != tt.numbers {
+			t.Errorf("numberRunes(%q) = %q, want %q", tt.text, string(got), tt.numbers)
+		}
+	}
+}
+
+func TestReorderVisualOrder(t *testing.T) {
+	//visual is the text in the order it is laid out, from the right edge for rtl
+	tests := []struct {
+		text   string
+		rtl    bool
+		visual string
+	}{
+		{"abc שלום def", false, "abc םולש def"},
+		{"שלום abc עולם", true, "שלום cba עולם"},
+		{"שלום 123 עולם", true, "שלום 321 עולם"},
+		{"abc שלום 123 def", false, "abc 123 םולש def"},
+		{"abc שלום 12.5 def", false, "abc 12.5 םולש def"},
+		//brackets of right to left runs are mirrored
+		{"(שלום) abc", false, "(םולש) abc"},
+		{"abc (שלום)", true, "cba )שלום("},
+		{"abc, שלום!", false, "abc, םולש!"},
+		//each line is reordered on its own
+		{"abc שלום\nשלום abc", false, "abc םולש\nםולש abc"},
+	}
+	for _, tt := range tests {
+		f := &Font{}
+		if tt.rtl {
+			f.dir = RightToLeft
+		}
+		text := []rune(tt.text)
+		visual, _, order := f.reorder(text, nil)
+		if string(visual) != tt.visual {
+			t.Errorf("reorder(%q, rtl %v) = %q, want %q", tt.text, tt.rtl, string(visual), tt.visual)
+		}
+		seen := make([]bool, len(text))
+		for _, i := range order {
+			seen[i] = true
+		}
+		for i, ok := range seen {
+			if !ok {
+				t.Errorf("reorder(%q) drops rune %d", tt.text, i)
+			}
+		}
+	}
+}
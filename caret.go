@@ -0,0 +1,56 @@
+package glfont
+
+import "fmt"
+
+// CaretOffset returns the x position, relative to the x passed to Printf, of the caret
+// before the rune at index in a string drawn at scale, kerning and spacing included.
+// Indices are counted in runes. An index past the end of the string is the caret at its
+// end, a negative one the caret at its start. For multi-line text, the position is on the
+// line of the rune.
+func (f *Font) CaretOffset(scale float32, index int, fs string, argv ...interface{}) float32 {
+
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	if index < 0 {
+		index = 0
+	}
+	if index > len(indices) {
+		index = len(indices)
+	}
+
+	var offset float32
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	f.layoutCarets(scale, indices, func(int, *character, *Font, float32, float32) {}, func(i int, x, y float32) {
+		if i == index {
+			offset = x
+		}
+	})
+
+	return offset
+}
+
+// IndexAtX returns the index of the caret nearest to localX, relative to the x passed to
+// Printf, in a string drawn at scale. It is the inverse of CaretOffset: positions before the
+// start of the string return 0 and past its end the number of runes. For multi-line text,
+// only the first line is hit.
+func (f *Font) IndexAtX(scale float32, localX float32, fs string, argv ...interface{}) int {
+
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	index, lineEnd := -1, false
+	var nearest float32
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	f.layoutCarets(scale, indices, func(int, *character, *Font, float32, float32) {}, func(i int, x, y float32) {
+		//stop at the carets of the following lines
+		if lineEnd = lineEnd || (i > 0 && indices[i-1] == '\n'); lineEnd {
+			return
+		}
+		if d := abs(x - localX); index < 0 || d < nearest {
+			index, nearest = i, d
+		}
+	})
+
+	return index
+}
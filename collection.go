@@ -0,0 +1,53 @@
+package glfont
+
+import "fmt"
+
+//collectionFace returns the font at index of the TrueType collection in data as a standalone
+//font, or data itself if it is a single font and index is 0
+func collectionFace(data []byte, index int) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "ttcf" {
+		if index != 0 {
+			return nil, fmt.Errorf("face index %d out of range: the font is not a collection", index)
+		}
+		return data, nil
+	}
+
+	numFonts := int(u32(data[8:]))
+	if index < 0 || index >= numFonts {
+		return nil, fmt.Errorf("face index %d out of range: the collection has %d faces", index, numFonts)
+	}
+	if len(data) < 12+4*numFonts {
+		return nil, fmt.Errorf("truncated font collection header")
+	}
+
+	//the table directory of the face, whose offsets are from the start of the collection
+	offset := int(u32(data[12+4*index:]))
+	if offset+12 > len(data) {
+		return nil, fmt.Errorf("truncated font collection face %d", index)
+	}
+	numTables := int(u16(data[offset+4:]))
+	dirSize := 12 + 16*numTables
+	if offset+dirSize > len(data) {
+		return nil, fmt.Errorf("truncated font collection face %d", index)
+	}
+
+	//copy the directory and the tables after it, pointing the records at the copies
+	face := append([]byte(nil), data[offset:offset+dirSize]...)
+	for i := 0; i < numTables; i++ {
+		record := 12 + 16*i
+		start, length := int(u32(face[record+8:])), int(u32(face[record+12:]))
+		if start+length > len(data) {
+			return nil, fmt.Errorf("truncated font collection face %d", index)
+		}
+		for len(face)%4 != 0 {
+			face = append(face, 0)
+		}
+		putU32(face[record+8:], uint32(len(face)))
+		face = append(face, data[start:start+length]...)
+	}
+	return face, nil
+}
+
+func putU32(b []byte, v uint32) {
+	b[0], b[1], b[2], b[3] = byte(v>>24), byte(v>>16), byte(v>>8), byte(v)
+}
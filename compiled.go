@@ -0,0 +1,118 @@
+package glfont
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/all-core/gl"
+)
+
+// CompiledText is a piece of text laid out once by Font.Compile, with its vertices kept in
+// a buffer of its own, so that static text such as labels is drawn every frame without
+// iterating its runes or allocating. The colors, decorations, background, faux italic and
+// y-axis of the font are baked in when it is compiled. The shadow, outline, faux bold,
+// opacity and blend mode are the ones of the font when it is drawn.
+type CompiledText struct {
+	font     *Font
+	scale    float32
+	text     []rune
+	vao      uint32
+	vbo      uint32
+	runs     []atlasRun
+	backdrop int32 // Number of vertices of the backgrounds, at the start of vbo.
+	stale    bool  // Lay the text out again on the next Draw.
+	context  int   // Context of the font vao and vbo were created in.
+}
+
+// Compile lays a string out at the given scale with its first baseline at 0, 0, for Draw to
+// draw it at any position. Delete must be called to release it when it is no longer used.
+func (f *Font) Compile(scale float32, fs string, argv ...interface{}) *CompiledText {
+	c := &CompiledText{font: f, scale: scale}
+	c.compile([]rune(fmt.Sprintf(fs, argv...)))
+	return c
+}
+
+// compile lays text out and uploads its vertices to a new buffer.
+func (c *CompiledText) compile(text []rune) {
+	c.release()
+	c.text, c.stale = text, false
+	if len(text) == 0 {
+		return
+	}
+
+	//lay the text out aside from the pending batch
+	f := c.font
+	quadFonts, backdrop := f.quadFonts, f.backdrop
+	f.quadFonts, f.backdrop = nil, nil
+	coords := f.appendText(nil, 0, 0, c.scale, text)
+	vertices, runs := f.groupByAtlas(f.backdrop, coords)
+	c.runs, c.backdrop = runs, int32(len(f.backdrop))
+	f.quadFonts, f.backdrop = quadFonts, backdrop
+
+	c.vao, c.vbo = f.newVertexArray(len(vertices)*pointSize, vertices, gl.STATIC_DRAW)
+	c.context = f.context
+}
+
+// release deletes the buffers of the compiled text.
+func (c *CompiledText) release() {
+	if c.vbo != 0 {
+		gl.DeleteBuffers(1, &c.vbo)
+		c.vbo = 0
+	}
+	if c.vao != 0 {
+		gl.DeleteVertexArrays(1, &c.vao)
+		c.vao = 0
+	}
+	c.runs = nil
+}
+
+// Draw draws the compiled text with its first baseline at x, y. Text batched with Begin
+// before the call is drawn first.
+func (c *CompiledText) Draw(x, y float32) error {
+	//the buffers went with the context the font was reloaded from, and their names may
+	//already belong to other objects of the new one, so they are forgotten, not deleted
+	if c.vao != 0 && c.context != c.font.context {
+		c.vao, c.vbo, c.runs = 0, 0, nil
+		c.stale = true
+	}
+	if c.stale {
+		c.compile(c.text)
+	}
+	if len(c.runs) == 0 {
+		return nil
+	}
+
+	f := c.font
+	f.flush()
+
+	if f.restoreState {
+		defer saveState(f.manageBlend).restore()
+	}
+
+	f.setupDraw()
+	gl.BindVertexArray(c.vao)
+	uploadPending(c.runs)
+	f.drawPasses(c.runs, c.backdrop, c.scale, x, y)
+
+	if !f.restoreState {
+		f.unbindDraw()
+	}
+
+	return nil
+}
+
+// Invalidate lays the same text out again on the next Draw, to bake the colors and other
+// settings of the font changed since it was compiled.
+func (c *CompiledText) Invalidate() {
+	c.stale = true
+}
+
+// Recompile replaces the compiled text with a new string, at the same scale.
+func (c *CompiledText) Recompile(fs string, argv ...interface{}) {
+	c.compile([]rune(fmt.Sprintf(fs, argv...)))
+}
+
+// Delete releases the buffers of the compiled text. It must not be drawn afterwards.
+func (c *CompiledText) Delete() {
+	c.release()
+	c.text = nil
+}
@@ -0,0 +1,161 @@
+package glfont
+
+import (
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+//textRun is a piece of a line of text drawn in a single color, to decorate
+type textRun struct {
+	x0, x1 float32 //horizontal extent of the glyph advances
+	y      float32 //baseline
+	color  color
+}
+
+//decorated reports whether the glyphs drawn need their runs collected
+func (f *Font) decorated() bool {
+	return (f.underline || f.strikethrough || f.background.a > 0) && f.dir != TopToBottom
+}
+
+//extendRun adds the advance of ch from the atlas of src drawn with its origin at x, y to the
+//last run of runs, or starts a new one if it is on another line or in another color
+func (f *Font) extendRun(runs []textRun, src *Font, ch *character, x, y float32, scale float32) []textRun {
+	if !f.decorated() {
+		return runs
+	}
+
+	x0, x1 := x, x+float32(ch.advance>>6)*src.glyphScale(scale)
+	if n := len(runs); n > 0 && runs[n-1].y == y && runs[n-1].color == f.color {
+		runs[n-1].x0 = min(runs[n-1].x0, x0)
+		runs[n-1].x1 = max(runs[n-1].x1, x1)
+		return runs
+	}
+	return append(runs, textRun{x0: x0, x1: x1, y: y, color: f.color})
+}
+
+//appendDecorations appends the lines drawn along runs, and adds the background behind them
+func (f *Font) appendDecorations(coords []point, runs []textRun, scale float32) []point {
+	if len(runs) > 0 && f.background.a > 0 {
+		x0, x1 := runs[0].x0, runs[0].x1
+		y0, y1 := runs[0].y, runs[0].y
+		for _, run := range runs {
+			x0, x1 = min(x0, run.x0), max(x1, run.x1)
+			y0, y1 = min(y0, run.y), max(y1, run.y)
+		}
+		pad := f.backgroundPadding * scale
+		top, bottom := y0-f.ascent*scale-pad, y1+f.descent*scale+pad
+		//the first line is the one at the largest y
+		if f.yAxis == YUp {
+			top, bottom = y1+f.ascent*scale+pad, y0-f.descent*scale-pad
+		}
+		f.backdrop = f.appendRect(f.backdrop, x0-pad, top, x1+pad, bottom, f.background)
+	}
+
+	for _, run := range runs {
+		if f.underline {
+			top := run.y + f.down(f.underlinePos*scale)
+			coords = f.appendRect(coords, run.x0, top, run.x1, top+f.down(max(f.underlineThick*scale, 1)), run.color)
+			f.quadFonts = append(f.quadFonts, f)
+		}
+		if f.strikethrough {
+			top := run.y - f.down(f.strikePos*scale)
+			coords = f.appendRect(coords, run.x0, top, run.x1, top+f.down(max(f.strikeThick*scale, 1)), run.color)
+			f.quadFonts = append(f.quadFonts, f)
+		}
+	}
+	return coords
+}
+
+// FillRect draws a rectangle filled with the color set with SetColor, in the coordinates the
+// text is drawn at and with the same projection or transform, for carets, selections and
+// highlights. x, y is its corner with the smallest coordinates. It is drawn without the shadow
+// and outline of the text, and beneath the text drawn in the same batch.
+func (f *Font) FillRect(x, y, w, h float32) error {
+	coords := f.scratch(f.drawScale)
+	f.backdrop = f.appendRect(f.backdrop, x, y, x+w, y+h, f.color)
+	return f.draw(coords)
+}
+
+//appendRect appends a quad filled with c from x0, y0 to x1, y1, drawn with the solid block of the atlas
+func (f *Font) appendRect(coords []point, x0, y0, x1, y1 float32, c color) []point {
+	//sample the middle of the block, away from the filtered edges
+	u := (float32(f.solid.x) + float32(f.solid.width)/2) / f.atlasWidth
+	v := (float32(f.solid.y) + float32(f.solid.height)/2) / f.atlasHeight
+
+	coords = append(coords, point{x0, y0, u, v, c.r, c.g, c.b, c.a})
+	coords = append(coords, point{x1, y0, u, v, c.r, c.g, c.b, c.a})
+	coords = append(coords, point{x0, y1, u, v, c.r, c.g, c.b, c.a})
+	coords = append(coords, point{x1, y0, u, v, c.r, c.g, c.b, c.a})
+	coords = append(coords, point{x0, y1, u, v, c.r, c.g, c.b, c.a})
+	coords = append(coords, point{x1, y1, u, v, c.r, c.g, c.b, c.a})
+
+	return coords
+}
+
+//loadDecorations sets the position and thickness of the underline and strikethrough from the
+//post and OS/2 tables of the font in data at scale. Fonts without them get an underline halfway
+//through the descent and a strikethrough halfway up the x-height.
+func (f *Font) loadDecorations(data []byte, scale int32) {
+	px := float32(scale)
+	f.underlinePos, f.underlineThick = f.descent/2, px/16
+	f.strikePos, f.strikeThick = f.ascent/3, px/16
+
+	ttf, err := sfnt.Parse(data)
+	if err != nil {
+		return
+	}
+	units := float32(ttf.UnitsPerEm())
+
+	if post := ttf.PostTable(); post != nil && post.UnderlineThickness > 0 {
+		f.underlinePos = -float32(post.UnderlinePosition) * px / units
+		f.underlineThick = float32(post.UnderlineThickness) * px / units
+	}
+	f.strikeThick = f.underlineThick
+
+	//yStrikeoutSize and yStrikeoutPosition, the position of the top of the line above the baseline
+	if os2 := sfntTable(data, "OS/2"); len(os2) >= 30 {
+		size, pos := int16(u16(os2[26:])), int16(u16(os2[28:]))
+		if size > 0 && pos > 0 {
+			f.strikeThick = float32(size) * px / units
+			f.strikePos = float32(pos) * px / units
+			return
+		}
+	}
+
+	var buf sfnt.Buffer
+	if metrics, err := ttf.Metrics(&buf, fixed.I(int(scale)), font.HintingNone); err == nil && metrics.XHeight > 0 {
+		f.strikePos = float32(metrics.XHeight)/64/2 + f.strikeThick/2
+	}
+}
+
+//sfntTable returns the table of a TrueType or OpenType font with the given tag, nil if it has none
+func sfntTable(data []byte, tag string) []byte {
+	if len(data) < 12 {
+		return nil
+	}
+	numTables := int(u16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		record := data[12+16*i:]
+		if len(record) < 16 {
+			return nil
+		}
+		if string(record[:4]) != tag {
+			continue
+		}
+		offset, length := u32(record[8:]), u32(record[12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil
+		}
+		return data[offset : offset+length]
+	}
+	return nil
+}
+
+func u16(b []byte) uint16 {
+	return uint16(b[0])<<8 | uint16(b[1])
+}
+
+func u32(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
@@ -0,0 +1,90 @@
+package glfont
+
+import (
+	"image"
+
+	"github.com/go-gl/gl/all-core/gl"
+	"golang.org/x/image/math/fixed"
+)
+
+// dynamicAtlas rasterizes glyphs outside of the loaded range the first time they are
+// used, and packs them in the free space of the atlas.
+type dynamicAtlas struct {
+	raster  *rasterizer
+	packer  *skyline
+	img     *image.Gray // CPU copy of the atlas the glyphs are drawn into.
+	glyphs  map[rune]*character
+	bounds  map[rune]fixed.Rectangle26_6
+	pending []rune // Glyphs packed but not uploaded to the texture yet.
+	rgba    bool
+	mipmaps bool
+}
+
+// lookup returns the character for r, packing it on first use. Runes the font has no
+// glyph for, or that no longer fit in the atlas, are reported missing.
+func (d *dynamicAtlas) lookup(r rune) (*character, bool) {
+	if char, ok := d.glyphs[r]; ok {
+		return char, char != nil
+	}
+
+	var char *character
+	if d.raster.glyphs.has(r) {
+		var err error
+		var bounds fixed.Rectangle26_6
+		char, bounds, err = d.raster.measure(r)
+		switch {
+		case err != nil:
+			char = nil
+		case char.empty():
+			//spaces take no room in the atlas
+		case d.packer.place(char):
+			d.bounds[r] = bounds
+			d.pending = append(d.pending, r)
+		default:
+			char = nil
+		}
+	}
+
+	d.glyphs[r] = char
+	return char, char != nil
+}
+
+// drawPending draws the pending glyphs into the CPU copy of the atlas, and returns
+// the runes of the ones drawn.
+func (d *dynamicAtlas) drawPending() []rune {
+	drawn := d.pending[:0]
+	for _, r := range d.pending {
+		if err := d.raster.draw(d.img, r, d.glyphs[r], d.bounds[r]); err == nil {
+			drawn = append(drawn, r)
+		}
+	}
+	d.pending = d.pending[:0]
+	return drawn
+}
+
+// upload draws the pending glyphs and copies them to the bound atlas texture.
+func (d *dynamicAtlas) upload() {
+	if len(d.pending) == 0 {
+		return
+	}
+
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	for _, r := range d.drawPending() {
+		char := d.glyphs[r]
+		rect := image.Rect(char.x, char.y, char.x+char.width, char.y+char.height)
+		if d.rgba {
+			rgba := coverageRGBA(d.img, rect)
+			gl.TexSubImage2D(gl.TEXTURE_2D, 0, int32(rect.Min.X), int32(rect.Min.Y), int32(rect.Dx()), int32(rect.Dy()),
+				gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgba.Pix))
+		} else {
+			gl.PixelStorei(gl.UNPACK_ROW_LENGTH, int32(d.img.Stride))
+			gl.TexSubImage2D(gl.TEXTURE_2D, 0, int32(rect.Min.X), int32(rect.Min.Y), int32(rect.Dx()), int32(rect.Dy()),
+				gl.RED, gl.UNSIGNED_BYTE, gl.Ptr(d.img.Pix[d.img.PixOffset(rect.Min.X, rect.Min.Y):]))
+			gl.PixelStorei(gl.UNPACK_ROW_LENGTH, 0)
+		}
+	}
+
+	if d.mipmaps {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+}
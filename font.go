@@ -2,9 +2,18 @@ package glfont
 
 import (
 	"fmt"
-	"os"
+	"image"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
 )
 
 // Direction represents the direction in which strings should be rendered.
@@ -17,16 +26,177 @@ const (
 	TopToBottom                  // E.g.: Chinese
 )
 
+// BlendMode represents how text is blended with what is beneath it.
+type BlendMode uint8
+
+// Known blend modes.
+const (
+	Straight      BlendMode = iota // Colors are not multiplied by their alpha, blended with SRC_ALPHA, ONE_MINUS_SRC_ALPHA.
+	Premultiplied                  // Colors are multiplied by their alpha, blended with ONE, ONE_MINUS_SRC_ALPHA.
+)
+
 // A Font allows rendering of text to an OpenGL context.
+//
+// Like the GL calls they make, the methods drawing text, loading and closing fonts and
+// changing how text looks must be called from the thread owning the GL context. The
+// methods measuring text, CanRender, CountMissing and Glyph can also be called from other
+// goroutines, concurrently with drawing.
 type Font struct {
-	fontChar    []*character
+	mu     sync.RWMutex // Guards the layout settings changed while text is being measured.
+	faceMu sync.Mutex   // Guards face and dynamic, which cache glyphs as they are used.
+
+	fontChar    map[rune]*character
+	ligatures   map[rune][]*ligature      // Ligatures of the loaded runes, by their first rune.
+	tofu        *character                // Hollow box drawn for missing runes.
+	solid       *character                // Fully covered block, drawn stretched for lines and boxes.
+	dynamic     *dynamicAtlas             // Glyphs added on demand, if enabled.
+	fallbacks   []*Font                   // Fonts drawing the runes this one lacks, in order.
+	dir         Direction                 // Direction in which the glyphs of a line advance.
+	face        font.Face                 // Source of the kerning between glyph pairs.
+	kerning     map[[2]rune]fixed.Int26_6 // Kerning of the glyph pairs of a baked font, which has no face.
+	source      []byte                    // Contents of the font file, kept to rebuild the atlas.
+	options     Options                   // Settings the font was loaded with, defaults included.
+	glslVersion uint                      // Version of the shader program created by the font, 0 if passed by the caller.
+	shaders     shaderSources             // Sources of the shader program created by the font.
+	resolution  [2]float32                // Window size last given to the shader program.
 	vao         uint32
 	vbo         uint32
+	vboSize     int     // Allocated size of vbo, in bytes.
+	coords      []point // Scratch slice reused for the quads of each call.
+	quadFonts   []*Font // Font whose atlas each quad of coords is drawn from.
+	grouped     []point // Scratch slice for coords grouped by atlas.
+	backdrop    []point // Background quads drawn beneath coords.
 	program     uint32
 	textureID   uint32 // Holds the glyph texture id.
+	context     int    // Number of times the font was reloaded in a new GL context.
 	color       color
 	atlasWidth  float32
 	atlasHeight float32
+	sdf         bool    // The atlas holds distance fields instead of coverage.
+	spread      int     // Atlas pixels covered by the distance fields on each side of the edges.
+	gamma       float32 // Gamma the coverage of the atlas is corrected with, 1 for none.
+	density     float32 // Atlas pixels per logical pixel, the DPI over 72.
+	lineHeight  float32 // Distance between two baselines, in pixels.
+	ascent      float32 // Distance from the baseline to the top of a line, in pixels.
+	descent     float32 // Distance from the baseline to the bottom of a line, in pixels.
+	locations
+
+	letterSpacing float32     // Extra space between glyphs, in pixels.
+	wordSpacing   float32     // Extra advance of spaces, in pixels.
+	lineSpacing   float32     // Multiplier of the line height between baselines.
+	tabWidth      int         // Distance between tab stops, in spaces.
+	restoreState  bool        // Restore the GL state changed while drawing.
+	manageBlend   bool        // Enable blending while drawing and set its function.
+	blendMode     BlendMode   // How the text is blended with the framebuffer.
+	pixelSnap     bool        // Round the glyph quads to whole pixels.
+	useLigatures  bool        // Draw the runes of ligatures with their single glyph.
+	useBidi       bool        // Reorder mixed direction text into visual order.
+	batching      bool        // Between Begin and End, coords holds the pending quads.
+	transform     *mgl32.Mat4 // Replaces the resolution mapping in the shader when set.
+	script        scriptMetrics
+	projection    *mgl32.Mat4 // Replaces the resolution mapping for every call when set, below transform.
+	yAxis         YAxis       // Direction in which y grows in the layout.
+	depthTest     bool        // Test the text of Printf3D against the depth buffer.
+
+	skipped     []rune // Runes of the font whose glyph failed to load.
+	showMissing bool   // Draw a placeholder for runes missing from the font.
+	missingRune rune   // Placeholder for missing runes, the tofu box if not loaded.
+
+	shadow     shadow
+	outline    outline
+	fauxBold   fauxBold
+	fauxItalic float32 // Horizontal shift of the glyphs per pixel above the baseline.
+	opacity    float32 // Multiplies the alpha of everything drawn.
+	drawScale  float32 // Scale of the text in coords, for the shadow and outline offsets.
+
+	gradient gradient
+
+	underline      bool
+	underlinePos   float32 // Distance from the baseline down to the top of the underline, in pixels.
+	underlineThick float32 // Thickness of the underline, in pixels.
+	strikethrough  bool
+	strikePos      float32 // Distance from the baseline up to the top of the strikethrough, in pixels.
+	strikeThick    float32 // Thickness of the strikethrough, in pixels.
+
+	background        color
+	backgroundPadding float32 // Space around the text inside the background, in pixels.
+}
+
+//shadow is drawn beneath the text, offset by x, y pixels times the text scale
+type shadow struct {
+	x, y  float32
+	color color
+}
+
+func (s shadow) enabled() bool {
+	return s.color.a > 0 && (s.x != 0 || s.y != 0)
+}
+
+//outline is drawn around the text, thickness pixels times the text scale wide
+type outline struct {
+	thickness float32
+	color     color
+}
+
+//outlineSteps is the number of copies of the text making up the outline ring
+const outlineSteps = 8
+
+func (o outline) enabled() bool {
+	return o.color.a > 0 && o.thickness > 0
+}
+
+//fauxBold thickens the glyphs by strength pixels times the text scale, and widens their
+//advance by as much if widen is set
+type fauxBold struct {
+	strength float32
+	widen    bool
+}
+
+//boldEdgeShift returns how much lower the distance field edge is drawn to thicken the
+//glyphs of the atlas of f by strength logical pixels, half on each side
+func (f *Font) boldEdgeShift(strength float32) float32 {
+	if f.spread == 0 {
+		return 0
+	}
+	//the field goes from 0.5 to 0 over spread atlas pixels
+	return min(strength*f.density/2*0.5/float32(f.spread), 0.5)
+}
+
+//gradient replaces the text color from the top to the bottom of each glyph,
+//or of each line if perLine is set
+type gradient struct {
+	enabled     bool
+	perLine     bool
+	top, bottom color
+}
+
+//at returns the color of the gradient t of the way from its top to its bottom
+func (g gradient) at(t float32) color {
+	t = max(0, min(t, 1))
+	return color{
+		r: g.top.r + (g.bottom.r-g.top.r)*t,
+		g: g.top.g + (g.bottom.g-g.top.g)*t,
+		b: g.top.b + (g.bottom.b-g.top.b)*t,
+		a: g.top.a + (g.bottom.a-g.top.a)*t,
+	}
+}
+
+//locations of the shader inputs, resolved once when the font is loaded
+type locations struct {
+	resolutionUniform  int32
+	colorUniform       int32
+	transformUniform   int32
+	useTransform       int32
+	offsetUniform      int32
+	sdfUniform         int32
+	edgeUniform        int32
+	premultiplyUniform int32
+	gammaUniform       int32
+	useVertexColor     int32
+	opacityUniform     int32
+	vertAttrib         uint32
+	texCoordAttrib     uint32
+	colorAttrib        uint32
 }
 
 type color struct {
@@ -36,20 +206,100 @@ type color struct {
 	a float32
 }
 
-type point [4]float32
+//point is a vertex of the glyph quads: position, atlas coordinates and color
+type point [8]float32
 
-//LoadFont loads the specified font at the given scale.
+//pointSize is the size of a point in the vertex buffer, in bytes
+const pointSize = 8 * 4
+
+//LoadFont loads the specified font at the given scale. TrueType (.ttf) fonts and OpenType
+//(.otf) fonts with either TrueType or CFF outlines are supported.
 func LoadFont(file string, scale int32, windowWidth int, windowHeight int, GLSLVersion uint) (*Font, error) {
-	fd, err := os.Open(file)
+	return LoadFontRange(file, scale, windowWidth, windowHeight, GLSLVersion, 32, 256)
+}
+
+// LoadFontRange loads the specified font at the given scale with the glyphs from low to high.
+// See LoadTrueTypeFont for the limits on the size of the range.
+func LoadFontRange(file string, scale int32, windowWidth int, windowHeight int, GLSLVersion uint, low, high rune) (*Font, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadFont(data, windowWidth, windowHeight, GLSLVersion, defaultShaders, Options{Scale: scale, Low: low, High: high})
+}
+
+// LoadFontCollection loads the face at index of a TrueType collection (.ttc) file at the given
+// scale. An index of 0 also loads a single font file.
+func LoadFontCollection(file string, index int, scale int32, windowWidth int, windowHeight int, GLSLVersion uint) (*Font, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadFont(data, windowWidth, windowHeight, GLSLVersion, defaultShaders, Options{Scale: scale, Index: index})
+}
+
+// LoadFontBytes loads a font from the raw contents of a ttf file at the given scale.
+// It is useful with fonts embedded in the binary.
+func LoadFontBytes(data []byte, scale int32, windowWidth int, windowHeight int, GLSLVersion uint) (*Font, error) {
+	return loadFont(data, windowWidth, windowHeight, GLSLVersion, defaultShaders, Options{Scale: scale})
+}
+
+// LoadFontShaders loads a font from the raw contents of a ttf file at the given scale like
+// LoadFontBytes, drawing it with the given GLSL vertex and fragment shader sources instead
+// of the default ones, without their #version line. An empty source selects the default
+// shader. See DefaultVertexShader for the inputs the shaders receive. An error is returned
+// if they do not compile or link.
+func LoadFontShaders(data []byte, scale int32, windowWidth int, windowHeight int, GLSLVersion uint, vertexShader, fragmentShader string) (*Font, error) {
+	shaders := defaultShaders
+	if vertexShader != "" {
+		shaders.vertex = terminate(vertexShader)
+	}
+	if fragmentShader != "" {
+		shaders.fragment = terminate(fragmentShader)
+	}
+	return loadFont(data, windowWidth, windowHeight, GLSLVersion, shaders, Options{Scale: scale})
+}
+
+// LoadFontSizes loads a font from the raw contents of a ttf file at each of the given scales,
+// parsing it once. Each font has its own atlas and settings, and must be closed on its own.
+func LoadFontSizes(data []byte, sizes []int32, windowWidth int, windowHeight int, GLSLVersion uint) ([]*Font, error) {
+	p, err := parseFont(data, 0)
 	if err != nil {
 		return nil, err
 	}
-	defer fd.Close()
 
-	// Configure the default font vertex and fragment shaders
-	program, err := newProgram(GLSLVersion, vertexFontShader, fragmentFontShader)
+	fonts := make([]*Font, 0, len(sizes))
+	for _, size := range sizes {
+		f, err := loadParsed(p, windowWidth, windowHeight, GLSLVersion, defaultShaders, Options{Scale: size})
+		if err != nil {
+			for _, loaded := range fonts {
+				loaded.Close()
+			}
+			return nil, err
+		}
+		fonts = append(fonts, f)
+	}
+
+	return fonts, nil
+}
+
+func loadFont(data []byte, windowWidth int, windowHeight int, GLSLVersion uint, shaders shaderSources, opts Options) (*Font, error) {
+	p, err := parseFont(data, opts.Index)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	return loadParsed(p, windowWidth, windowHeight, GLSLVersion, shaders, opts)
+}
+
+//loadParsed loads the parsed font p with a new program built from shaders
+func loadParsed(p parsedFont, windowWidth int, windowHeight int, GLSLVersion uint, shaders shaderSources, opts Options) (*Font, error) {
+	// Configure the font vertex and fragment shaders
+	program, err := newProgram(GLSLVersion, shaders.vertex, shaders.fragment)
+	if err != nil {
+		return nil, err
 	}
 
 	// Activate corresponding render state
@@ -59,25 +309,458 @@ func LoadFont(file string, scale int32, windowWidth int, windowHeight int, GLSLV
 	resUniform := gl.GetUniformLocation(program, gl.Str("resolution\x00"))
 	gl.Uniform2f(resUniform, float32(windowWidth), float32(windowHeight))
 
-	return LoadTrueTypeFont(program, fd, scale, 32, 256, LeftToRight)
+	f, err := p.load(program, opts)
+	if err != nil {
+		gl.DeleteProgram(program)
+		return nil, err
+	}
+	f.glslVersion = GLSLVersion
+	f.shaders = shaders
+	f.resolution = [2]float32{float32(windowWidth), float32(windowHeight)}
+	return f, nil
 }
 
-//SetColor allows you to set the text color to be used when you draw the text
+//SetColor allows you to set the text color to be used when you draw the text.
+//The channels range from 0 to 1, values outside of it are clamped: divide 0-255 values by 255.
 func (f *Font) SetColor(red float32, green float32, blue float32, alpha float32) {
-	f.color.r = red
-	f.color.g = green
-	f.color.b = blue
-	f.color.a = alpha
+	f.color.r = clamp01(red)
+	f.color.g = clamp01(green)
+	f.color.b = clamp01(blue)
+	f.color.a = clamp01(alpha)
+}
+
+// SetColorHex sets the text color like SetColor from a "#RRGGBB" or "#RRGGBBAA" string,
+// with or without the #. An error is returned, and the color left unchanged, if hex is not
+// in one of these forms.
+func (f *Font) SetColorHex(hex string) error {
+	digits := strings.TrimPrefix(hex, "#")
+	if len(digits) != 6 && len(digits) != 8 {
+		return fmt.Errorf("invalid hex color %q", hex)
+	}
+	if len(digits) == 6 {
+		digits += "ff"
+	}
+
+	rgba, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return fmt.Errorf("invalid hex color %q", hex)
+	}
+
+	f.SetColor(float32(rgba>>24&0xff)/255, float32(rgba>>16&0xff)/255, float32(rgba>>8&0xff)/255, float32(rgba&0xff)/255)
+	return nil
+}
+
+// SetGradient draws text fading from the top color at the top of each glyph to the bottom
+// color at its bottom, instead of the color set with SetColor. See SetGradientPerLine to
+// stretch it over whole lines instead.
+func (f *Font) SetGradient(topR, topG, topB, topA, botR, botG, botB, botA float32) {
+	f.gradient.enabled = true
+	f.gradient.top = color{r: topR, g: topG, b: topB, a: topA}
+	f.gradient.bottom = color{r: botR, g: botG, b: botB, a: botA}
+}
+
+// SetGradientPerLine chooses whether the gradient goes from the ascent to the descent
+// of each line of text, the same for all its glyphs, rather than over each glyph.
+func (f *Font) SetGradientPerLine(perLine bool) {
+	f.gradient.perLine = perLine
+}
+
+// ClearGradient draws text with the color set with SetColor again.
+func (f *Font) ClearGradient() {
+	f.gradient.enabled = false
+}
+
+// SetShadow draws text a second time beneath itself in the given color, offset by
+// offsetX, offsetY pixels multiplied by the text scale. A zero offset or a transparent
+// color disables the shadow, which is the default. Its alpha is multiplied by the alpha
+// of the text above it.
+func (f *Font) SetShadow(offsetX, offsetY float32, red, green, blue, alpha float32) {
+	//text batched so far keeps the previous shadow
+	f.flush()
+
+	f.shadow = shadow{x: offsetX, y: offsetY, color: color{r: red, g: green, b: blue, a: alpha}}
+}
+
+// SetOutline draws an outline of the given color around text, thickness pixels multiplied
+// by the text scale wide, beneath the text and above its shadow. A thickness of 0 or a
+// transparent color disables the outline, which is the default. Its alpha is multiplied
+// by the alpha of the text above it.
+func (f *Font) SetOutline(thickness float32, red, green, blue, alpha float32) {
+	//text batched so far keeps the previous outline
+	f.flush()
+
+	f.outline = outline{thickness: thickness, color: color{r: red, g: green, b: blue, a: alpha}}
+}
+
+// SetFauxBold draws text thicker, with its glyphs widened by strength pixels multiplied by
+// the text scale, for emphasis with fonts without a bold weight. Glyphs from SDF atlases are
+// thickened smoothly, others are drawn several times side by side. A strength of 0 disables
+// it, which is the default. See SetFauxBoldAdvance to make room for the added width.
+func (f *Font) SetFauxBold(strength float32) {
+	//text batched so far keeps the previous weight
+	f.flush()
+
+	f.mu.Lock()
+	f.fauxBold.strength = strength
+	f.mu.Unlock()
+}
+
+// SetFauxBoldAdvance chooses whether the advance of each glyph grows by the faux bold
+// strength, so that the thickened glyphs do not touch. It is disabled by default.
+func (f *Font) SetFauxBoldAdvance(widen bool) {
+	f.mu.Lock()
+	f.fauxBold.widen = widen
+	f.mu.Unlock()
+}
+
+// SetFauxItalic slants text for emphasis with fonts without an italic style, shifting
+// each point of the glyphs right by skew times its height above the baseline, so 0.2 leans
+// them by about 11 degrees. Only the glyph quads are sheared: the width of measured text
+// is the same. A skew of 0 disables it, which is the default.
+func (f *Font) SetFauxItalic(skew float32) {
+	f.fauxItalic = skew
+}
+
+// SetUnderline chooses whether text is drawn with a line under each of its lines,
+// in the text color, at the position and thickness defined by the font.
+// It is ignored for TopToBottom fonts.
+func (f *Font) SetUnderline(underline bool) {
+	f.underline = underline
+}
+
+// SetStrikethrough chooses whether text is drawn with a line through each of its lines,
+// in the text color, at the strikeout position of the font or halfway up its x-height.
+// It is ignored for TopToBottom fonts.
+func (f *Font) SetStrikethrough(strikethrough bool) {
+	f.strikethrough = strikethrough
+}
+
+// SetBackground draws a rectangle of the given color behind text, as large as the text
+// plus padding pixels multiplied by the text scale on each side. Backgrounds are drawn
+// beneath the text, its shadow and its outline, and beneath all the text of a batch.
+// A transparent color disables the background, which is the default.
+func (f *Font) SetBackground(red, green, blue, alpha float32, padding float32) {
+	f.background = color{r: red, g: green, b: blue, a: alpha}
+	f.backgroundPadding = padding
+}
+
+// SetOpacity multiplies the alpha of the text, its shadow, outline and background by
+// opacity, from 0 for invisible to 1 for opaque, which is the default. It is independent
+// from the colors, so text can be faded in and out without changing them.
+func (f *Font) SetOpacity(opacity float32) {
+	//text batched so far keeps the previous opacity
+	if opacity != f.opacity {
+		f.flush()
+	}
+
+	f.opacity = opacity
+}
+
+// SetShowMissing chooses whether runes missing from the font are drawn with a placeholder,
+// or skipped. It is enabled by default.
+func (f *Font) SetShowMissing(show bool) {
+	f.mu.Lock()
+	f.showMissing = show
+	f.mu.Unlock()
+}
+
+// SetMissingGlyph chooses the rune drawn in place of runes missing from the font, such as '?'.
+// If r is not loaded either, a hollow box is drawn, which is the default.
+func (f *Font) SetMissingGlyph(r rune) {
+	f.mu.Lock()
+	f.missingRune = r
+	f.mu.Unlock()
+}
+
+// SetLetterSpacing adds px pixels, multiplied by the text scale, between every pair of glyphs.
+// Negative values move glyphs closer together. The default is 0.
+func (f *Font) SetLetterSpacing(px float32) {
+	f.mu.Lock()
+	f.letterSpacing = px
+	f.mu.Unlock()
+}
+
+// SetWordSpacing adds px pixels, multiplied by the text scale, to the advance of spaces,
+// U+0020 and the no-break space U+00A0, on top of the letter spacing. Negative values
+// tighten the text. The default is 0. Lines are never wrapped on no-break spaces.
+func (f *Font) SetWordSpacing(px float32) {
+	f.mu.Lock()
+	f.wordSpacing = px
+	f.mu.Unlock()
+}
+
+// SetLineSpacing sets the distance between the baselines of multi-line text as a
+// multiple of the font line height: 1.0 is the natural line height, 1.5 adds 50%.
+// The default is 1.0.
+func (f *Font) SetLineSpacing(multiplier float32) {
+	f.mu.Lock()
+	f.lineSpacing = multiplier
+	f.mu.Unlock()
+}
+
+// SetTabWidth sets the distance between tab stops as a number of spaces.
+// A tab moves the text to the next stop from the start of the line. The default is 4.
+func (f *Font) SetTabWidth(spaces int) {
+	f.mu.Lock()
+	f.tabWidth = spaces
+	f.mu.Unlock()
+}
+
+// SetPixelSnap chooses whether the glyphs are drawn at whole pixel positions, which keeps
+// text crisp at its loaded size, especially with mipmaps disabled. It is disabled by default
+// so that moving text glides smoothly between pixels.
+func (f *Font) SetPixelSnap(snap bool) {
+	f.pixelSnap = snap
+}
+
+// SetBlendMode chooses how text is blended with the framebuffer. With Premultiplied the
+// shader outputs colors multiplied by their alpha, for compositors expecting premultiplied
+// alpha. The default is Straight.
+func (f *Font) SetBlendMode(mode BlendMode) {
+	//text batched so far keeps the previous mode
+	if mode != f.blendMode {
+		f.flush()
+	}
+
+	f.blendMode = mode
+}
+
+// SetRestoreState chooses whether drawing text saves the bound program, VAO, buffer and
+// texture and the blending state, and restores them afterwards. It is enabled by default.
+// When disabled, drawing leaves them unbound and blending disabled, which is cheaper.
+func (f *Font) SetRestoreState(restore bool) {
+	f.restoreState = restore
+}
+
+// SetManageBlend chooses whether drawing text enables blending and sets the blend function
+// for the blend mode, then disables blending or restores its state. It is enabled by
+// default. Engines managing blending themselves can disable it: text is then drawn with
+// the blending state they set up, which must blend it like the blend mode of the font.
+func (f *Font) SetManageBlend(manage bool) {
+	f.manageBlend = manage
 }
 
 // UpdateResolution passes the new framebuffer size to the font shader
 func (f *Font) UpdateResolution(windowWidth int, windowHeight int) {
+	f.resolution = [2]float32{float32(windowWidth), float32(windowHeight)}
 	gl.UseProgram(f.program)
-	resUniform := gl.GetUniformLocation(f.program, gl.Str("resolution\x00"))
-	gl.Uniform2f(resUniform, float32(windowWidth), float32(windowHeight))
+	gl.Uniform2f(f.resolutionUniform, float32(windowWidth), float32(windowHeight))
 	gl.UseProgram(0)
 }
 
+// Ascent returns the distance from the baseline to the top of a line of text at the given scale.
+func (f *Font) Ascent(scale float32) float32 {
+	return f.ascent * scale
+}
+
+// Descent returns the distance from the baseline to the bottom of a line of text at the given scale.
+// The value is positive even though it extends below the baseline.
+func (f *Font) Descent(scale float32) float32 {
+	return f.descent * scale
+}
+
+// CenterYInBox returns the y of the baseline to pass to Printf for a single line of text at
+// scale to be centered vertically in a box boxHeight high, from its ascent to its descent.
+// boxTop is the y of the top edge of the box, which is its largest y with YUp.
+func (f *Font) CenterYInBox(boxTop, boxHeight, scale float32) float32 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return boxTop + f.down(boxHeight/2+(f.ascent-f.descent)*scale/2)
+}
+
+// LineHeight returns the distance between two consecutive baselines at the given scale.
+func (f *Font) LineHeight(scale float32) float32 {
+	return f.lineHeight * scale
+}
+
+// TextureID returns the OpenGL texture holding the glyph atlas, to sample it in other shaders.
+// The coverage, or distance field with AtlasOptions.SDF, is in the red channel of a single
+// channel texture, or in the alpha channel of an RGBA texture with AtlasOptions.RGBA. See Glyph
+// for where each glyph is in it. It changes when the font is reloaded.
+func (f *Font) TextureID() uint32 {
+	return f.textureID
+}
+
+// AtlasSize returns the width and height of the atlas texture, in pixels.
+func (f *Font) AtlasSize() (w, h float32) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.atlasWidth, f.atlasHeight
+}
+
+// Close releases the OpenGL texture, buffers and shader program owned by the font.
+// The font must not be used after Close. Like every other GL call, it must be
+// made on the thread that owns the GL context. Calling Close twice is a no-op.
+func (f *Font) Close() {
+	if f.textureID != 0 {
+		gl.DeleteTextures(1, &f.textureID)
+		f.textureID = 0
+	}
+	if f.vbo != 0 {
+		gl.DeleteBuffers(1, &f.vbo)
+		f.vbo = 0
+	}
+	if f.vao != 0 {
+		gl.DeleteVertexArrays(1, &f.vao)
+		f.vao = 0
+	}
+	if f.program != 0 {
+		gl.DeleteProgram(f.program)
+		f.program = 0
+	}
+	f.fontChar = nil
+	f.ligatures = nil
+	f.dynamic = nil
+	f.source = nil
+}
+
+// Reload recreates the OpenGL texture, buffers and shader program of the font after the
+// context they were created in was lost, such as when it is recreated on Android or after
+// the system sleeps, which leaves the font drawing nothing. The atlas is rebuilt from the
+// font file and glyphs added to a dynamic atlas are kept. It must be called on the thread
+// owning the new context, once it is current, and for each fallback font too.
+// Fonts loaded with a shader program of the caller must be reloaded with ReloadProgram.
+// Text compiled with the font is laid out again in new buffers on its next Draw.
+func (f *Font) Reload() error {
+	if f.source == nil {
+		return f.noSource("reloaded")
+	}
+	if f.glslVersion == 0 {
+		return fmt.Errorf("font was loaded with a caller program, use ReloadProgram")
+	}
+
+	program, err := newProgram(f.glslVersion, f.shaders.vertex, f.shaders.fragment)
+	if err != nil {
+		return err
+	}
+
+	gl.UseProgram(program)
+	gl.Uniform2f(gl.GetUniformLocation(program, gl.Str("resolution\x00")), f.resolution[0], f.resolution[1])
+	gl.UseProgram(0)
+
+	if err := f.ReloadProgram(program); err != nil {
+		gl.DeleteProgram(program)
+		return err
+	}
+	return nil
+}
+
+// ReloadProgram is like Reload for fonts loaded with LoadTrueTypeFont and its variants,
+// drawing with program, created by the caller in the new context.
+func (f *Font) ReloadProgram(program uint32) error {
+	if f.source == nil {
+		return f.noSource("reloaded")
+	}
+
+	//rasterize the atlas again first, for the font to be left as it was if it fails
+	var rebuilt *Font
+	var gray *image.Gray
+	if f.dynamic == nil {
+		var maxSize int32
+		gl.GetIntegerv(gl.MAX_TEXTURE_SIZE, &maxSize)
+
+		var err error
+		rebuilt, gray, err = buildFont(f.source, f.options, int(maxSize))
+		if err != nil {
+			return err
+		}
+	}
+
+	//the old handles belong to the lost context, there is nothing left to delete
+	f.program = program
+	f.locations = lookupLocations(program)
+	f.textureID, f.vao, f.vbo, f.vboSize = 0, 0, 0, 0
+	f.context++
+
+	if f.dynamic != nil {
+		//the CPU copy of the atlas already holds every glyph
+		f.faceMu.Lock()
+		f.dynamic.drawPending()
+		f.faceMu.Unlock()
+		f.createGLObjects(f.dynamic.img)
+		return nil
+	}
+
+	f.mu.Lock()
+	f.fontChar, f.ligatures, f.tofu, f.solid = rebuilt.fontChar, rebuilt.ligatures, rebuilt.tofu, rebuilt.solid
+	f.skipped = rebuilt.skipped
+	f.atlasWidth, f.atlasHeight = rebuilt.atlasWidth, rebuilt.atlasHeight
+	f.mu.Unlock()
+
+	f.createGLObjects(gray)
+	return nil
+}
+
+//noSource returns the error of a method needing the font file, which closed and baked
+//fonts do not have, saying the font cannot be what
+func (f *Font) noSource(what string) error {
+	if f.fontChar != nil {
+		return fmt.Errorf("baked fonts cannot be %s, load the font file instead", what)
+	}
+	return fmt.Errorf("font is closed")
+}
+
+// Rebuild rasterizes the glyphs of the font again at the given scale, from the font file
+// kept since it was loaded, into the texture the font already owns. It is much cheaper than
+// loading the font again to change the size of its text, and keeps its settings. Glyphs
+// added to a dynamic atlas are added again as they are drawn, and CompiledText has to be
+// recompiled. Like every other GL call, it must be made on the thread that owns the GL context.
+func (f *Font) Rebuild(scale int32) error {
+	opts := f.options
+	opts.Scale = scale
+	return f.rebuild(opts)
+}
+
+// SetContentScale rasterizes the glyphs of the font again for a display with the given
+// device pixel ratio, such as 2 when the window moves to a HiDPI monitor, like Rebuild.
+// It replaces the DPI the font was loaded with by 72 times scale: text keeps its size in
+// logical pixels, the units UpdateResolution and Printf take, and is drawn with as many
+// atlas pixels as there are device pixels.
+func (f *Font) SetContentScale(scale float32) error {
+	if scale <= 0 {
+		return fmt.Errorf("content scale %g is not positive", scale)
+	}
+	opts := f.options
+	opts.DPI = 72 * float64(scale)
+	return f.rebuild(opts)
+}
+
+//rebuild rasterizes the atlas of the font again with opts, into the texture it owns
+func (f *Font) rebuild(opts Options) error {
+	if f.source == nil {
+		return f.noSource("rebuilt")
+	}
+
+	var maxSize int32
+	gl.GetIntegerv(gl.MAX_TEXTURE_SIZE, &maxSize)
+
+	rebuilt, gray, err := buildFont(f.source, opts, int(maxSize))
+	if err != nil {
+		return err
+	}
+
+	//draw what is batched with the old atlas
+	f.flush()
+
+	//readers check the face and dynamic atlas holding mu before taking faceMu
+	f.mu.Lock()
+	f.faceMu.Lock()
+	f.face, f.dynamic = rebuilt.face, rebuilt.dynamic
+	f.faceMu.Unlock()
+	f.options = opts
+	f.fontChar, f.ligatures, f.tofu, f.solid = rebuilt.fontChar, rebuilt.ligatures, rebuilt.tofu, rebuilt.solid
+	f.skipped = rebuilt.skipped
+	f.atlasWidth, f.atlasHeight = rebuilt.atlasWidth, rebuilt.atlasHeight
+	f.density = rebuilt.density
+	f.ascent, f.descent, f.lineHeight = rebuilt.ascent, rebuilt.descent, rebuilt.lineHeight
+	f.underlinePos, f.underlineThick = rebuilt.underlinePos, rebuilt.underlineThick
+	f.strikePos, f.strikeThick = rebuilt.strikePos, rebuilt.strikeThick
+	f.mu.Unlock()
+
+	f.uploadAtlas(gray)
+	return nil
+}
+
 //Printf draws a string to the screen, takes a list of arguments like printf
 func (f *Font) Printf(x, y float32, scale float32, fs string, argv ...interface{}) error {
 
@@ -87,103 +770,295 @@ func (f *Font) Printf(x, y float32, scale float32, fs string, argv ...interface{
 		return nil
 	}
 
-	lowChar := rune(32)
-
-	//setup blending mode
-	gl.Enable(gl.BLEND)
-	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	return f.draw(f.appendText(f.scratch(scale), x, y, scale, indices))
+}
 
-	// Activate corresponding render state
-	gl.UseProgram(f.program)
-	//set text color
-	gl.Uniform4f(gl.GetUniformLocation(f.program, gl.Str("textColor\x00")), f.color.r, f.color.g, f.color.b, f.color.a)
-
-	var coords []point
-
-	// Iterate through all characters in string
-	for i := range indices {
-		//get rune
-		runeIndex := indices[i]
-
-		var ch *character
-		//skip runes that are not in font chacter range
-		if int(runeIndex)-int(lowChar) > len(f.fontChar) || runeIndex < lowChar {
-			// print a ?
-			ch = f.fontChar[int(rune('?'))-int(lowChar)]
-		} else {
-			// find rune in fontChar list
-			ch = f.fontChar[runeIndex-lowChar]
-		}
+// DrawRunes draws runes to the screen like Printf, without formatting them, so that text
+// such as an editor buffer is drawn as is, % signs included, and without copying it.
+func (f *Font) DrawRunes(x, y float32, scale float32, runes []rune) error {
+	if len(runes) == 0 {
+		return nil
+	}
 
-		//calculate position and size for current rune
-		xpos := x + float32(ch.bearingH)*scale
-		ypos := y - float32(ch.height-ch.bearingV)*scale
-		w := float32(ch.width) * scale
-		h := float32(ch.height) * scale
-
-		//set quad positions
-		var x1 = xpos
-		var x2 = xpos + w
-		var y1 = ypos
-		var y2 = ypos + h
-
-		coords = append(coords, point{x1, y1, float32(ch.x) / f.atlasWidth, float32(ch.y) / f.atlasHeight})
-		coords = append(coords, point{x2, y1, float32(ch.x+ch.width) / f.atlasWidth, float32(ch.y) / f.atlasHeight})
-		coords = append(coords, point{x1, y2, float32(ch.x) / f.atlasWidth, float32(ch.y+ch.height) / f.atlasHeight})
-		coords = append(coords, point{x2, y1, float32(ch.x+ch.width) / f.atlasWidth, float32(ch.y) / f.atlasHeight})
-		coords = append(coords, point{x1, y2, float32(ch.x) / f.atlasWidth, float32(ch.y+ch.height) / f.atlasHeight})
-		coords = append(coords, point{x2, y2, float32(ch.x+ch.width) / f.atlasWidth, float32(ch.y+ch.height) / f.atlasHeight})
-
-		// Now advance cursors for next glyph (note that advance is number of 1/64 pixels)
-		x += float32((ch.advance >> 6)) * scale // Bitshift by 6 to get value in pixels (2^6 = 64 (divide amount of 1/64th pixels by 64 to get amount of pixels))
-	}
-
-	gl.BindVertexArray(f.vao)
-	gl.ActiveTexture(gl.TEXTURE0)
-	gl.BindTexture(gl.TEXTURE_2D, f.textureID)
-	gl.BindBuffer(gl.ARRAY_BUFFER, f.vbo)
-	gl.BufferData(gl.ARRAY_BUFFER, len(coords)*16, gl.Ptr(coords), gl.DYNAMIC_DRAW)
-	gl.DrawArrays(gl.TRIANGLES, 0, int32(len(coords)))
-	gl.BindVertexArray(0)
-	gl.BindTexture(gl.TEXTURE_2D, 0)
-	gl.UseProgram(0)
-	gl.Disable(gl.BLEND)
+	return f.draw(f.appendText(f.scratch(scale), x, y, scale, runes))
+}
 
-	return nil
+// Print draws s to the screen like Printf, without formatting it, so that text coming
+// from users is drawn as is rather than having its % signs interpreted as verbs.
+func (f *Font) Print(x, y float32, scale float32, s string) error {
+	return f.DrawRunes(x, y, scale, []rune(s))
 }
 
-//Width returns the width of a piece of text in pixels
-func (f *Font) Width(scale float32, fs string, argv ...interface{}) float32 {
+// Rect is an axis aligned rectangle in the coordinates text is drawn at, from its corner
+// with the smallest coordinates.
+type Rect struct {
+	X, Y, W, H float32
+}
 
-	var width float32
+// DrawString draws a string to the screen like Printf, and returns the bounds of the lines
+// drawn: their advances across and from the ascent of the first one to the descent of the
+// last one down, as measured by MeasureString without the separate pass.
+func (f *Font) DrawString(x, y float32, scale float32, fs string, argv ...interface{}) (Rect, error) {
 
 	indices := []rune(fmt.Sprintf(fs, argv...))
 
 	if len(indices) == 0 {
-		return 0
+		return Rect{X: x, Y: y}, nil
 	}
 
-	lowChar := rune(32)
+	coords, bounds := f.appendTextBounds(f.scratch(scale), x, y, scale, indices)
+	return bounds, f.draw(coords)
+}
 
-	// Iterate through all characters in string
-	for i := range indices {
+// AddFallback adds a font to draw the runes this font has no glyph for. Fallbacks are
+// searched in the order they were added, including their own fallbacks, and drawn with
+// this font's color and effects on the same baseline. Load them at the same scale for
+// their glyphs to match in size.
+func (f *Font) AddFallback(fallback *Font) {
+	if fallback == nil || fallback.inChain(f) {
+		return
+	}
+	f.mu.Lock()
+	f.fallbacks = append(f.fallbacks, fallback)
+	f.mu.Unlock()
+}
 
-		//get rune
-		runeIndex := indices[i]
+//inChain reports whether g is f or one of its fallbacks
+func (f *Font) inChain(g *Font) bool {
+	if f == g {
+		return true
+	}
+	for _, fb := range f.fallbacks {
+		if fb.inChain(g) {
+			return true
+		}
+	}
+	return false
+}
 
-		//skip runes that are not in font chacter range
-		if int(runeIndex)-int(lowChar) > len(f.fontChar) || runeIndex < lowChar {
-			fmt.Printf("%c %d\n", runeIndex, runeIndex)
-			continue
+// CanRender reports whether the font or its fallbacks have a glyph for r, or handles it in
+// layout like newlines and tabs.
+func (f *Font) CanRender(r rune) bool {
+	if r == '\n' || r == '\t' {
+		return true
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	_, _, ok := f.lookupGlyph(r)
+	return ok
+}
+
+// LoadedRunes returns the runes in the atlas of the font, in increasing order: the ones
+// loaded with it, and the ones added to its dynamic atlas so far. Runes its fallbacks
+// draw are not included, see CanRender for these.
+func (f *Font) LoadedRunes() []rune {
+	f.mu.RLock()
+	runes := make([]rune, 0, len(f.fontChar))
+	for r := range f.fontChar {
+		runes = append(runes, r)
+	}
+
+	//rebuild swaps the dynamic atlas holding both locks
+	f.faceMu.Lock()
+	if dynamic := f.dynamic; dynamic != nil {
+		for r, char := range dynamic.glyphs {
+			if char != nil {
+				runes = append(runes, r)
+			}
 		}
+	}
+	f.faceMu.Unlock()
+	f.mu.RUnlock()
+
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return runes
+}
+
+// SkippedRunes returns the runes of the loaded range the font has a glyph for that could not
+// be measured or rasterized, in increasing order. Loading skips them instead of failing, so
+// that a font with a few broken glyphs is still usable, and they are drawn as missing runes.
+// It is empty for a font whose glyphs all loaded.
+func (f *Font) SkippedRunes() []rune {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return append([]rune(nil), f.skipped...)
+}
 
-		//find rune in fontChar list
-		ch := f.fontChar[runeIndex-lowChar]
+// GlyphInfo holds the metrics of a glyph, in pixels of the atlas at the scale the font was
+// loaded with, and its position in the atlas texture. Atlas pixels are logical pixels
+// multiplied by the DPI over 72.
+type GlyphInfo struct {
+	Width, Height int     // Size of the glyph bitmap.
+	Advance       float32 // Distance from the origin of the glyph to the origin of the next one.
+	BearingH      int     // Distance from the origin to the left edge of the bitmap.
+	BearingV      int     // Distance from the baseline down to the bottom edge of the bitmap.
 
-		// Now advance cursors for next glyph (note that advance is number of 1/64 pixels)
-		width += float32((ch.advance >> 6)) * scale // Bitshift by 6 to get value in pixels (2^6 = 64 (divide amount of 1/64th pixels by 64 to get amount of pixels))
+	U0, V0, U1, V1 float32 // Atlas coordinates of the top left and bottom right corners of the bitmap.
+}
 
+// Glyph returns the metrics of the glyph of r, from the font or its fallbacks, and
+// whether there is one.
+func (f *Font) Glyph(r rune) (GlyphInfo, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	ch, src, ok := f.lookupGlyph(r)
+	if !ok {
+		return GlyphInfo{}, false
 	}
+	return GlyphInfo{
+		Width:    ch.width,
+		Height:   ch.height,
+		Advance:  float32(ch.advance) / 64,
+		BearingH: ch.bearingH,
+		BearingV: ch.bearingV,
+		U0:       float32(ch.x) / src.atlasWidth,
+		V0:       float32(ch.y) / src.atlasHeight,
+		U1:       float32(ch.x+ch.width) / src.atlasWidth,
+		V1:       float32(ch.y+ch.height) / src.atlasHeight,
+	}, true
+}
 
+// Advance returns the distance the pen moves after drawing r at scale, from the font or its
+// fallbacks, or 0 if none of them has a glyph for it. It is the advance of the glyph alone,
+// without the kerning with the runes around it and the letter or word spacing, so the
+// width of a string is not the sum of the advances of its runes; see Width for that.
+func (f *Font) Advance(scale float32, r rune) float32 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	ch, src, ok := f.lookupGlyph(r)
+	if !ok {
+		return 0
+	}
+	return float32(ch.advance>>6) * src.glyphScale(scale)
+}
+
+// CountMissing returns how many runes of a string the font cannot render. They are drawn
+// with the missing glyph placeholder, or skipped if it is disabled.
+func (f *Font) CountMissing(fs string, argv ...interface{}) int {
+	missing := 0
+	for _, r := range fmt.Sprintf(fs, argv...) {
+		if !f.CanRender(r) {
+			missing++
+		}
+	}
+	return missing
+}
+
+//Width returns the width of a piece of text in pixels. For multi-line text it is the width of the widest line.
+func (f *Font) Width(scale float32, fs string, argv ...interface{}) float32 {
+	width, _ := f.MeasureString(scale, fs, argv...)
+	return width
+}
+
+// MeasureString returns the width and height of a piece of text in pixels.
+// The width is the one of the widest line and the height is the distance between
+// the first and last lines plus one line height, or the height of the tallest glyph
+// if it is larger.
+func (f *Font) MeasureString(scale float32, fs string, argv ...interface{}) (w, h float32) {
+	return f.measure(scale, []rune(fmt.Sprintf(fs, argv...)))
+}
+
+// WidthString returns the width of s in pixels like Width, without formatting it.
+func (f *Font) WidthString(scale float32, s string) float32 {
+	width, _ := f.measure(scale, []rune(s))
 	return width
 }
+
+// FitScale returns the largest scale at which text fits in maxWidth by maxHeight, as measured
+// by MeasureString, to draw labels as large as their box allows. A maxHeight of 0 or less
+// leaves the height unbounded. Text is not wrapped: its newlines start the lines it is
+// measured on. It returns 0 for empty text, or text with no width and height.
+func (f *Font) FitScale(text string, maxWidth, maxHeight float32) float32 {
+	indices := []rune(text)
+
+	//every distance of the layout grows linearly with the scale
+	w, h := f.measure(1, indices)
+	if w <= 0 && h <= 0 {
+		return 0
+	}
+	scale := float32(math.Inf(1))
+	if w > 0 {
+		scale = maxWidth / w
+	}
+	if maxHeight > 0 && h > 0 {
+		scale = min(scale, maxHeight/h)
+	}
+	if math.IsInf(float64(scale), 1) {
+		return 0
+	}
+
+	//rounding may leave the text a hair too wide
+	for i := 0; i < 4 && scale > 0; i++ {
+		if w, h := f.measure(scale, indices); w <= maxWidth && (maxHeight <= 0 || h <= maxHeight) {
+			break
+		}
+		scale = math.Nextafter32(scale, 0)
+	}
+	return max(scale, 0)
+}
+
+//measure returns the width and height of indices as documented by MeasureString
+func (f *Font) measure(scale float32, indices []rune) (w, h float32) {
+
+	var tallest float32
+
+	if len(indices) == 0 {
+		return 0, 0
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	width, lines := f.layout(scale, indices, func(_ int, ch *character, src *Font, x, y float32) {
+		tallest = max(tallest, float32(ch.height)*src.glyphScale(1))
+	})
+
+	height := max(float32(lines-1)*f.lineHeight*f.lineSpacing+f.lineHeight, tallest) * scale
+
+	//the lines of vertical text are columns, side by side
+	if f.dir == TopToBottom {
+		return float32(lines-1)*f.lineAdvance(scale) + f.lineHeight*scale, width
+	}
+
+	return width, height
+}
+
+// TightBounds returns the union of the ink rectangles of the glyphs of a piece of text
+// drawn with its first baseline at 0, 0, rather than the box given by their advances
+// and the line height. Its top is at the cap height for a line of capitals, which
+// helps centering a line on its letters. It returns an empty Rect for text without ink.
+func (f *Font) TightBounds(scale float32, fs string, argv ...interface{}) Rect {
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var x0, y0, x1, y1 float32
+	inked := false
+	f.layout(scale, indices, func(_ int, ch *character, src *Font, x, y float32) {
+		if ch.empty() {
+			return
+		}
+
+		//leave out the empty pixels around the distance fields
+		gs := src.glyphScale(scale)
+		pad := float32(src.spread) * gs
+		left := x + float32(ch.bearingH)*gs + pad
+		right := left + float32(ch.width)*gs - 2*pad
+		top := y - f.down(float32(ch.height-ch.bearingV)*gs-pad)
+		bottom := top + f.down(float32(ch.height)*gs-2*pad)
+		if f.yAxis == YUp {
+			top, bottom = bottom, top
+		}
+
+		if !inked {
+			x0, y0, x1, y1 = left, top, right, bottom
+			inked = true
+			return
+		}
+		x0, y0 = min(x0, left), min(y0, top)
+		x1, y1 = max(x1, right), max(y1, bottom)
+	})
+
+	return Rect{X: x0, Y: y0, W: x1 - x0, H: y1 - y0}
+}
@@ -0,0 +1,97 @@
+package glfont
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+//loadTestFont builds Go Regular with opts, without OpenGL
+func loadTestFont(t *testing.T, opts Options) *Font {
+	t.Helper()
+	f, _, err := buildFont(goregular.TTF, opts, 8192)
+	if err != nil {
+		t.Fatalf("buildFont: %v", err)
+	}
+	return f
+}
+
+func TestLoadedRangeRendersEveryRune(t *testing.T) {
+	const low, high = 32, 126
+	f := loadTestFont(t, Options{Scale: 20, Low: low, High: high})
+
+	var all []rune
+	quads := 0
+	for r := rune(low); r <= high; r++ {
+		char, ok := f.fontChar[r]
+		if !ok {
+			t.Errorf("rune %q of the range is not loaded", r)
+			continue
+		}
+		all = append(all, r)
+
+		want := 6
+		if char.empty() {
+			want = 0
+		}
+		quads += want
+		if got := len(f.appendText(nil, 0, 0, 1, []rune{r})); got != want {
+			t.Errorf("rune %q is drawn with %d vertices, want %d", r, got, want)
+		}
+		if w := f.WidthString(1, string(r)); w <= 0 {
+			t.Errorf("rune %q measures %g wide", r, w)
+		}
+	}
+
+	//the last rune of the range is drawn with the others
+	if got := len(f.appendText(nil, 0, 0, 1, all)); got != quads {
+		t.Errorf("the range is drawn with %d vertices, want %d", got, quads)
+	}
+	if got, want := f.WidthString(1, string(all)), f.WidthString(1, string(all[:len(all)-1])); got <= want {
+		t.Errorf("%q adds nothing to the width of the range", rune(high))
+	}
+}
+
+func TestDPIDoublesGlyphBitmaps(t *testing.T) {
+	normal := loadTestFont(t, Options{Scale: 20})
+	dense := loadTestFont(t, Options{Scale: 20, DPI: 144})
+
+	a, b := normal.fontChar['A'], dense.fontChar['A']
+	for _, size := range []struct {
+		name   string
+		normal int
+		dense  int
+	}{
+		{"width", a.width, b.width},
+		{"height", a.height, b.height},
+	} {
+		if ratio := float32(size.dense) / float32(size.normal); ratio < 1.8 || ratio > 2.2 {
+			t.Errorf("the %s of A is %d at 144 DPI and %d at 72 DPI, not about twice as much", size.name, size.dense, size.normal)
+		}
+	}
+
+	//text keeps its size in logical pixels
+	if got, want := dense.Advance(1, 'A'), normal.Advance(1, 'A'); abs(got-want) > 1 {
+		t.Errorf("A advances %g at 144 DPI and %g at 72 DPI", got, want)
+	}
+	if got, want := dense.WidthString(1, "AAAA"), normal.WidthString(1, "AAAA"); abs(got-want) > 2 {
+		t.Errorf("AAAA is %g wide at 144 DPI and %g at 72 DPI", got, want)
+	}
+}
+
+func TestRebuildBakedFont(t *testing.T) {
+	//a baked font has glyphs but no font file
+	f := loadTestFont(t, Options{Scale: 20})
+	f.source = nil
+
+	err := f.Rebuild(30)
+	if err == nil || !strings.Contains(err.Error(), "baked") {
+		t.Fatalf("Rebuild of a baked font = %v, want an error about baked fonts", err)
+	}
+
+	f.fontChar = nil
+	if err := f.Rebuild(30); err == nil || err.Error() != "font is closed" {
+		t.Fatalf("Rebuild of a closed font = %v, want font is closed", err)
+	}
+}
@@ -0,0 +1,86 @@
+package glfont
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// WidthGraphemes returns the width of a piece of text in pixels like Width, advancing once per
+// grapheme cluster rather than once per rune: a base with its combining marks, an emoji
+// with its modifiers and the emoji joined to it by zero width joiners, or a pair of
+// regional indicators making a flag, each advance by the glyph of their first rune.
+func (f *Font) WidthGraphemes(scale float32, fs string, argv ...interface{}) float32 {
+
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	if len(indices) == 0 {
+		return 0
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var bases []rune
+	for _, cluster := range graphemes(indices) {
+		//"\r\n" is a newline
+		bases = append(bases, cluster[len(cluster)-1])
+		if cluster[0] != '\r' {
+			bases[len(bases)-1] = cluster[0]
+		}
+	}
+
+	width, _ := f.layout(scale, bases, func(int, *character, *Font, float32, float32) {})
+	return width
+}
+
+// graphemes splits text into grapheme clusters. It approximates the extended grapheme
+// clusters of Unicode for marks, emoji sequences and flags, and keeps "\r\n" together.
+func graphemes(text []rune) [][]rune {
+	var clusters [][]rune
+	start := 0
+	for i := 1; i <= len(text); i++ {
+		if i < len(text) && !graphemeBreak(text[start:i], text[i]) {
+			continue
+		}
+		clusters = append(clusters, text[start:i])
+		start = i
+	}
+	return clusters
+}
+
+// graphemeBreak reports whether a cluster ends between cluster and the rune r following it.
+func graphemeBreak(cluster []rune, r rune) bool {
+	last := cluster[len(cluster)-1]
+	switch {
+	case last == '\r':
+		return r != '\n'
+	case last == '\n' || r == '\r' || r == '\n':
+		return true
+	case last == zeroWidthJoiner:
+		return false
+	case r == zeroWidthJoiner || isGraphemeExtend(r):
+		return false
+	case isRegionalIndicator(last) && isRegionalIndicator(r):
+		//flags are pairs of regional indicators
+		pairs := 0
+		for i := len(cluster) - 1; i >= 0 && isRegionalIndicator(cluster[i]); i-- {
+			pairs++
+		}
+		return pairs%2 == 0
+	}
+	return true
+}
+
+const zeroWidthJoiner = '\u200d'
+
+// isGraphemeExtend reports whether r extends the cluster before it: marks, variation
+// selectors, emoji skin tone modifiers and tags.
+func isGraphemeExtend(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc, unicode.Variation_Selector) ||
+		(r >= 0x1f3fb && r <= 0x1f3ff) ||
+		(r >= 0xe0020 && r <= 0xe007f)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
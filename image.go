@@ -0,0 +1,94 @@
+package glfont
+
+import (
+	"image"
+	imgcolor "image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+
+	"github.com/go-gl/gl/all-core/gl"
+)
+
+//imageAtlasSize is the largest atlas RenderToImage packs glyphs into
+const imageAtlasSize = 8192
+
+// RenderToImage draws text with the ttf font in data at scale into a new image, without
+// OpenGL. The image is as large as the text measured with MeasureString, with its first
+// baseline at the font ascent, and is transparent where the text is not drawn. It lays
+// text out like Printf, so it can be used to test the layout or to draw text on a server.
+func RenderToImage(data []byte, scale int32, col imgcolor.Color, text string) (*image.RGBA, error) {
+	//an atlas of only the runes of text, with the space tab stops are measured with
+	runes := []rune(text)
+	opts := Options{Scale: scale, Ranges: []RuneRange{{' ', ' '}}, Runes: runes}
+	f, atlas, err := buildFont(data, opts, imageAtlasSize)
+	if err != nil {
+		return nil, err
+	}
+
+	w, h := f.MeasureString(1, "%s", text)
+	img := image.NewRGBA(image.Rect(0, 0, int(math.Ceil(float64(w))), int(math.Ceil(float64(h)))))
+
+	//the coverage of the atlas is the alpha of the glyphs
+	mask := &image.Alpha{Pix: atlas.Pix, Stride: atlas.Stride, Rect: atlas.Rect}
+	fill := image.NewUniform(col)
+	f.layout(1, runes, func(_ int, ch *character, src *Font, x, y float32) {
+		//top left corner of the glyph, the same as in appendGlyph
+		px := int(math.Floor(float64(x))) + ch.bearingH
+		py := int(math.Floor(float64(y+f.ascent))) - (ch.height - ch.bearingV)
+		r := image.Rect(px, py, px+ch.width, py+ch.height)
+		draw.DrawMask(img, r, fill, image.ZP, mask, image.Pt(ch.x, ch.y), draw.Over)
+	})
+
+	return img, nil
+}
+
+// SaveAtlas writes the atlas texture of the font as a grayscale PNG file, to inspect how
+// the glyphs are packed and sampled. It reads the texture back from the GPU, so like every
+// other GL call it must be made on the thread that owns the GL context.
+func (f *Font) SaveAtlas(path string) error {
+	img := f.readAtlas()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+//readAtlas returns the coverage of the atlas texture, read back from the GPU
+func (f *Font) readAtlas() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, int(f.atlasWidth), int(f.atlasHeight)))
+
+	var texture, alignment int32
+	gl.GetIntegerv(gl.TEXTURE_BINDING_2D, &texture)
+	gl.GetIntegerv(gl.PACK_ALIGNMENT, &alignment)
+
+	gl.BindTexture(gl.TEXTURE_2D, f.textureID)
+	if f.dynamic != nil {
+		f.faceMu.Lock()
+		f.dynamic.upload()
+		f.faceMu.Unlock()
+	}
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	if f.options.Atlas.RGBA {
+		//the coverage is in the alpha of white texels
+		rgba := image.NewRGBA(img.Rect)
+		gl.GetTexImage(gl.TEXTURE_2D, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgba.Pix))
+		for i := range img.Pix {
+			img.Pix[i] = rgba.Pix[4*i+3]
+		}
+	} else {
+		gl.GetTexImage(gl.TEXTURE_2D, 0, gl.RED, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+	}
+
+	gl.PixelStorei(gl.PACK_ALIGNMENT, alignment)
+	gl.BindTexture(gl.TEXTURE_2D, uint32(texture))
+
+	return img
+}
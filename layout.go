@@ -0,0 +1,278 @@
+package glfont
+
+import (
+	"math"
+	"unicode"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// layout walks text the way it is drawn, calling fn for every glyph with the index
+// of its rune in text and the position of its origin on the baseline relative to the start of the first line.
+// The lines go towards larger y, or smaller y with YUp.
+// It returns the width of the widest line and the number of lines.
+// With RightToLeft the lines start at their right edge and x decreases. With
+// TopToBottom the lines are columns centered on x that start at y and go down,
+// the next one to their left, and the returned width is the height of the tallest.
+func (f *Font) layout(scale float32, text []rune, fn func(i int, ch *character, src *Font, x, y float32)) (width float32, lines int) {
+	return f.layoutCarets(scale, text, fn, nil)
+}
+
+// layoutCarets is like layout, also calling caret with the position of the caret before
+// each rune of text and at its end, on the baseline, or at the top of vertical columns.
+func (f *Font) layoutCarets(scale float32, text []rune, fn func(i int, ch *character, src *Font, x, y float32), caret func(i int, x, y float32)) (width float32, lines int) {
+	return f.layoutScripts(scale, text, nil, fn, caret)
+}
+
+// layoutScripts is like layoutCarets, drawing the runes of text whose script is
+// Superscript or Subscript smaller and off the baseline. scripts is nil or holds the
+// script of each rune of text.
+func (f *Font) layoutScripts(lineScale float32, text []rune, scripts []Script, fn func(i int, ch *character, src *Font, x, y float32), caret func(i int, x, y float32)) (width float32, lines int) {
+	var x, y float32
+	scale := lineScale
+	var prev rune
+	var prevSrc *Font
+	var base *character //last glyph combining marks are placed over
+	var baseX, baseScale float32
+	rtl := f.dir == RightToLeft
+	vertical := f.dir == TopToBottom
+	lines = 1
+	skip := 0 //runes left of the last ligature
+
+	//mixed direction text is walked in visual order, reporting the logical index of the runes
+	index := func(i int) int { return i }
+	if f.useBidi && !vertical {
+		var order []int
+		text, scripts, order = f.reorder(text, scripts)
+		index = func(i int) int { return order[i] }
+	}
+
+	for i, r := range text {
+		//the runes of a ligature after the first one are drawn with it
+		if skip > 0 {
+			skip--
+			continue
+		}
+
+		if caret != nil {
+			caret(f.caretAt(index(i), x, y))
+		}
+
+		//runes off the baseline are smaller
+		var rise float32
+		if scripts != nil {
+			scale, rise = f.scriptScale(scripts[i], lineScale)
+		}
+
+		//start a new line below the current one
+		if r == '\n' {
+			width = max(width, abs(x))
+			x = 0
+			y += f.lineAdvance(lineScale)
+			prev, base = 0, nil
+			lines++
+			continue
+		}
+
+		//move to the next tab stop of the line
+		if r == '\t' {
+			if stop := f.tabStop(lineScale); stop > 0 {
+				x = float32(math.Floor(float64(abs(x)/stop))+1) * stop
+				if rtl {
+					x = -x
+				}
+			}
+			prev, base = 0, nil
+			continue
+		}
+
+		//ligatures replace the runes they are made of with a single glyph
+		var ch *character
+		var src *Font
+		merged := 0
+		if f.useLigatures && !vertical {
+			ch, merged = f.ligature(text[i:])
+			src = f
+		}
+
+		// find rune in fontChar list, or the fallbacks
+		if merged == 0 {
+			var ok bool
+			ch, src, ok = f.lookupGlyph(r)
+			if !ok {
+				// use the placeholder for runes that are not in font chacter range
+				if !f.showMissing {
+					continue
+				}
+				r = f.missingRune
+				ch, src, ok = f.lookupGlyph(r)
+				if !ok {
+					r = 0
+					ch, src = f.tofu, f
+				}
+			}
+		}
+
+		//combining marks are centered over the glyph before them, without advancing
+		if base != nil && !vertical && unicode.Is(unicode.Mn, r) {
+			center := baseX + (float32(base.bearingH)+float32(base.width)/2)*baseScale
+			fn(index(i), ch, src, center-(float32(ch.bearingH)+float32(ch.width)/2)*src.glyphScale(scale), f.down(y-rise))
+			continue
+		}
+
+		//move the pair closer or further apart as defined by the font and the letter spacing
+		if prev != 0 && r != 0 {
+			var kern float32
+			//kerning only applies to horizontal text
+			if prevSrc == src && !vertical {
+				//right to left, the previous glyph is the right one of the pair
+				if rtl {
+					kern = float32(src.kern(r, prev)) / 64 * src.glyphScale(scale)
+				} else {
+					kern = float32(src.kern(prev, r)) / 64 * src.glyphScale(scale)
+				}
+			}
+			x += f.forward(kern + f.letterSpacing*scale)
+		}
+
+		// Now advance cursors for next glyph (note that advance is number of 1/64 pixels)
+		advance := float32((ch.advance >> 6)) * src.glyphScale(scale) // Bitshift by 6 to get value in pixels (2^6 = 64 (divide amount of 1/64th pixels by 64 to get amount of pixels))
+		if f.fauxBold.widen {
+			advance += f.fauxBold.strength * scale
+		}
+		if r == ' ' || r == noBreakSpace {
+			advance += f.wordSpacing * scale
+		}
+
+		//right to left, the origin of the glyph is at its left, one advance before the pen
+		base, baseX, baseScale = ch, x, src.glyphScale(scale)
+		if rtl {
+			x -= advance
+			baseX = x
+			fn(index(i), ch, src, x, f.down(y-rise))
+		} else if vertical {
+			//x is the distance down the column and y the distance between columns
+			fn(index(i), ch, src, -y-advance/2, f.down(x+f.ascent*scale))
+			x += float32(ch.vadvance>>6) * src.glyphScale(scale)
+		} else {
+			fn(index(i), ch, src, x, f.down(y-rise))
+			x += advance
+		}
+		prev, prevSrc = r, src
+
+		//the carets between the runes of a ligature divide it evenly
+		if merged > 1 {
+			skip = merged - 1
+			prev = text[i+skip]
+			if caret != nil {
+				start := x - f.forward(advance)
+				for k := 1; k < merged; k++ {
+					caret(f.caretAt(index(i+k), start+f.forward(advance)*float32(k)/float32(merged), y))
+				}
+			}
+		}
+	}
+
+	if caret != nil {
+		caret(f.caretAt(len(text), x, y))
+	}
+
+	return max(width, abs(x)), lines
+}
+
+// noBreakSpace is a space that lines are not wrapped on.
+const noBreakSpace = '\u00a0'
+
+// scriptScale returns the scale of the runes of script in a line drawn at scale, and how
+// far above the baseline they are drawn.
+func (f *Font) scriptScale(script Script, scale float32) (float32, float32) {
+	switch script {
+	case Superscript:
+		return scale * f.script.scale, f.script.rise * f.ascent * scale
+	case Subscript:
+		return scale * f.script.scale, -f.script.drop * f.ascent * scale
+	}
+	return scale, 0
+}
+
+// caretAt returns the caret i at the pen position x, y of layout.
+func (f *Font) caretAt(i int, x, y float32) (int, float32, float32) {
+	if f.dir == TopToBottom {
+		return i, -y, f.down(x)
+	}
+	return i, x, f.down(y)
+}
+
+// forward returns d as a distance along the direction of the lines of the font.
+func (f *Font) forward(d float32) float32 {
+	if f.dir == RightToLeft {
+		return -d
+	}
+	return d
+}
+
+// down returns d as a distance down the screen, which is towards smaller y with YUp.
+func (f *Font) down(d float32) float32 {
+	if f.yAxis == YUp {
+		return -d
+	}
+	return d
+}
+
+// kern returns the kerning of the pair of runes a, b in 1/64 pixels of the atlas.
+func (f *Font) kern(a, b rune) fixed.Int26_6 {
+	//the face caches the glyph indices it looks up
+	//baked fonts only know the kerning of the pairs of their runes
+	if f.face == nil {
+		return f.kerning[[2]rune{a, b}]
+	}
+
+	f.faceMu.Lock()
+	defer f.faceMu.Unlock()
+	return f.face.Kern(a, b)
+}
+
+// lookupGlyph returns the character for r and the font whose atlas holds it,
+// searching the fallbacks when the font lacks it.
+func (f *Font) lookupGlyph(r rune) (*character, *Font, bool) {
+	if ch, ok := f.lookup(r); ok {
+		return ch, f, true
+	}
+	for _, fb := range f.fallbacks {
+		fb.mu.RLock()
+		ch, src, ok := fb.lookupGlyph(r)
+		fb.mu.RUnlock()
+		if ok {
+			return ch, src, true
+		}
+	}
+	return nil, nil, false
+}
+
+// nextLine returns the origin of the line following the one at x, y.
+func (f *Font) nextLine(x, y float32, scale float32) (float32, float32) {
+	if f.dir == TopToBottom {
+		return x - f.lineAdvance(scale), y
+	}
+	return x, y + f.down(f.lineAdvance(scale))
+}
+
+// lineAdvance returns the distance between two baselines, including the line spacing.
+func (f *Font) lineAdvance(scale float32) float32 {
+	return f.lineHeight * f.lineSpacing * scale
+}
+
+// tabStop returns the distance between two tab stops, or 0 if the font has no space glyph.
+func (f *Font) tabStop(scale float32) float32 {
+	space, ok := f.lookup(' ')
+	if !ok {
+		return 0
+	}
+	return float32(f.tabWidth) * float32(space.advance>>6) * f.glyphScale(scale)
+}
+
+// glyphScale returns the scale of the glyphs in the atlas drawn at scale, whose sizes are in
+// atlas pixels rather than logical pixels when the font is loaded at a DPI other than 72.
+func (f *Font) glyphScale(scale float32) float32 {
+	return scale / f.density
+}
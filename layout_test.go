@@ -0,0 +1,187 @@
+package glfont
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestKerningNarrowsPairs(t *testing.T) {
+	//Go Regular has no kerning table
+	data, err := ioutil.ReadFile("testdata/luxisr.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _, err := buildFont(data, Options{Scale: 40}, 8192)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kern := float32(f.kern('A', 'V')) / 64
+	if kern >= 0 {
+		t.Fatalf("the test font does not kern AV closer, kern = %g", kern)
+	}
+	kerned := f.WidthString(1, "AV")
+
+	//without a face or baked pairs there is no kerning
+	f.face = nil
+	plain := f.WidthString(1, "AV")
+
+	if kerned >= plain {
+		t.Errorf("AV is %g wide with kerning and %g without", kerned, plain)
+	}
+	if kerned != plain+kern {
+		t.Errorf("AV is %g wide with kerning, want %g", kerned, plain+kern)
+	}
+}
+
+//glyphOrigins returns the origin of each glyph of text laid out by f at scale 1, by the index of its rune
+func glyphOrigins(f *Font, text string) map[int][2]float32 {
+	origins := make(map[int][2]float32)
+	f.layout(1, []rune(text), func(i int, _ *character, _ *Font, x, y float32) {
+		origins[i] = [2]float32{x, y}
+	})
+	return origins
+}
+
+func TestRightToLeftDescends(t *testing.T) {
+	//Go Regular has no Hebrew glyphs
+	data, err := ioutil.ReadFile("testdata/DejaVuSans.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := []rune("שלום עולם")
+	f, _, err := buildFont(data, Options{Scale: 20, Direction: RightToLeft, Runes: append(text, 'a', 'b', 'c')}, 8192)
+	if err != nil {
+		t.Fatalf("buildFont: %v", err)
+	}
+	for _, r := range text {
+		if _, ok := f.fontChar[r]; !ok {
+			t.Fatalf("%q is not loaded", r)
+		}
+	}
+
+	origins := glyphOrigins(f, string(text))
+	if len(origins) != len(text) {
+		t.Fatalf("%d glyphs laid out for %d runes", len(origins), len(text))
+	}
+	for i := 1; i < len(text); i++ {
+		if origins[i][0] >= origins[i-1][0] {
+			t.Errorf("rune %d at x %g is not left of rune %d at x %g", i, origins[i][0], i-1, origins[i-1][0])
+		}
+	}
+	if origins[0][0] >= 0 {
+		t.Errorf("the first rune starts at x %g, right of the origin of the line", origins[0][0])
+	}
+
+	//reordered, the Latin word embedded in the Hebrew line is still read from left to right
+	f.SetBidi(true)
+	origins = glyphOrigins(f, "שלום abc")
+	for i := 1; i < 4; i++ {
+		if origins[i][0] >= origins[i-1][0] {
+			t.Errorf("Hebrew rune %d at x %g is not left of rune %d at x %g", i, origins[i][0], i-1, origins[i-1][0])
+		}
+	}
+	for i := 6; i < 8; i++ {
+		if origins[i][0] <= origins[i-1][0] {
+			t.Errorf("Latin rune %d at x %g is not right of rune %d at x %g", i, origins[i][0], i-1, origins[i-1][0])
+		}
+	}
+	if origins[7][0] >= origins[3][0] {
+		t.Errorf("the Latin word ends at x %g, right of the Hebrew word at x %g", origins[7][0], origins[3][0])
+	}
+}
+
+func TestTopToBottomColumns(t *testing.T) {
+	f := loadTestFont(t, Options{Scale: 20, Direction: TopToBottom})
+
+	//two columns of three glyphs, the newline between them at index 3
+	origins := glyphOrigins(f, "abc\ndef")
+	for _, i := range []int{1, 2, 5, 6} {
+		if origins[i][1] <= origins[i-1][1] {
+			t.Errorf("rune %d at y %g is not below rune %d at y %g", i, origins[i][1], i-1, origins[i-1][1])
+		}
+	}
+	for _, i := range []int{4, 5, 6} {
+		for _, j := range []int{0, 1, 2} {
+			if origins[i][0] >= origins[j][0] {
+				t.Errorf("rune %d of the second column at x %g is not left of rune %d at x %g", i, origins[i][0], j, origins[j][0])
+			}
+		}
+	}
+}
+
+func TestYUpMirrorsYDown(t *testing.T) {
+	f := loadTestFont(t, Options{Scale: 20})
+	const baseline = 100
+
+	down := f.appendText(nil, 10, baseline, 1, []rune("Ag\nyQ"))
+	f.SetYAxis(YUp)
+	up := f.appendText(nil, 10, baseline, 1, []rune("Ag\nyQ"))
+
+	if len(up) != len(down) || len(down) == 0 {
+		t.Fatalf("%d vertices with YUp and %d with YDown", len(up), len(down))
+	}
+	for i := range down {
+		if up[i][0] != down[i][0] {
+			t.Errorf("vertex %d at x %g with YUp and %g with YDown", i, up[i][0], down[i][0])
+		}
+		if want := 2*baseline - down[i][1]; abs(up[i][1]-want) > 1e-3 {
+			t.Errorf("vertex %d at y %g with YUp, want %g mirroring %g", i, up[i][1], want, down[i][1])
+		}
+	}
+}
+
+func TestTruncateEllipsis(t *testing.T) {
+	f := loadTestFont(t, Options{Scale: 20, Low: 32, High: 126})
+	text := []rune("a long line of text")
+	dots := f.lineWidth(1, []rune("..."))
+
+	if got := string(f.truncate(1, dots+1, text)); got != "..." {
+		t.Errorf("without U+2026 the text is cut to %q, want ...", got)
+	}
+
+	//the ellipsis of a fallback is used over three dots
+	fb := loadTestFont(t, Options{Scale: 20, Runes: []rune("…")})
+	f.AddFallback(fb)
+	got := f.truncate(1, f.lineWidth(1, []rune("a long…")), text)
+	if string(got) != "a long…" {
+		t.Errorf("with a fallback ellipsis the text is cut to %q, want a long…", string(got))
+	}
+
+	if got := f.truncate(1, 1, text); len(got) != 0 {
+		t.Errorf("text narrower than the ellipsis is cut to %q, want nothing", string(got))
+	}
+}
+
+func TestJustifiedMixedDirection(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/DejaVuSans.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _, err := buildFont(data, Options{Scale: 20}, 8192)
+	if err != nil {
+		t.Fatalf("buildFont: %v", err)
+	}
+	f.SetBidi(true)
+
+	//the Hebrew words are laid out in the opposite order to the one they are stored in
+	line := []rune("abc שלום עולם de")
+	plain := f.appendText(nil, 0, 0, 1, line)
+	width := f.lineWidth(1, line) + 30
+	justified := f.appendJustified(nil, 0, 0, 1, width, line)
+	if len(justified) != len(plain) {
+		t.Fatalf("%d vertices justified, %d without", len(justified), len(plain))
+	}
+
+	//quads in visual order: abc, the first word seen from the left, the second one, de
+	gap := float32(10)
+	want := []float32{0, 0, 0, gap, gap, gap, gap, 2 * gap, 2 * gap, 2 * gap, 2 * gap, 3 * gap, 3 * gap}
+	if len(plain) != 6*len(want) {
+		t.Fatalf("%d quads laid out, want %d", len(plain)/6, len(want))
+	}
+	for q, shift := range want {
+		if got := justified[6*q][0] - plain[6*q][0]; abs(got-shift) > 0.01 {
+			t.Errorf("glyph %d moved by %g, want %g", q, got, shift)
+		}
+	}
+}
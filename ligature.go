@@ -0,0 +1,238 @@
+package glfont
+
+import (
+	"image"
+	"sort"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+//ligature is a glyph drawn in place of a sequence of runes, like fi
+type ligature struct {
+	runes  []rune // Runes replaced, the first one included.
+	glyph  sfnt.GlyphIndex
+	char   *character
+	bounds fixed.Rectangle26_6
+	face   *otfFace // Face drawing glyph, which has no rune of its own.
+}
+
+// SetLigatures enables substituting the runes of the ligatures the font defines, like fi,
+// fl and ffi, with their single glyph. It changes the advances of the text, and only
+// applies to the runes loaded with the font, to horizontal text. Ligatures are read from
+// the liga feature of the GSUB table of the font when it is loaded. They are disabled by default.
+func (f *Font) SetLigatures(enabled bool) {
+	f.mu.Lock()
+	f.useLigatures = enabled
+	f.mu.Unlock()
+}
+
+//ligature returns the character of the ligature starting text, and the number of runes it
+//replaces, or 0 if there is none
+func (f *Font) ligature(text []rune) (*character, int) {
+	for _, lig := range f.ligatures[text[0]] {
+		if len(lig.runes) > len(text) {
+			continue
+		}
+		match := true
+		for i, r := range lig.runes {
+			if text[i] != r {
+				match = false
+				break
+			}
+		}
+		if match {
+			return lig.char, len(lig.runes)
+		}
+	}
+	return nil, 0
+}
+
+//ligatures returns the ligatures of the font in data made of runes of loaded, measured at the
+//size of the rasterizer, in the order they are tried
+func (ra *rasterizer) ligatures(data []byte, loaded map[rune]*character) []*ligature {
+	gsub := sfntTable(data, "GSUB")
+	if gsub == nil {
+		return nil
+	}
+	otf, err := sfnt.Parse(data)
+	if err != nil {
+		return nil
+	}
+	face := otfOutlines{otf}.newFace(float64(ra.scale), ra.dpi, ra.hinting).(*otfFace)
+
+	//the rune of each glyph, the smallest when several runes share one
+	runes := make([]rune, 0, len(loaded))
+	for r := range loaded {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] > runes[j] })
+	glyphRunes := make(map[sfnt.GlyphIndex]rune)
+	for _, r := range runes {
+		if i, err := otf.GlyphIndex(&face.buf, r); err == nil && i != 0 {
+			glyphRunes[i] = r
+		}
+	}
+
+	var ligatures []*ligature
+	for _, sub := range parseLigatures(gsub) {
+		lig := &ligature{glyph: sfnt.GlyphIndex(sub.glyph), face: face}
+		for _, g := range sub.components {
+			r, ok := glyphRunes[sfnt.GlyphIndex(g)]
+			if !ok {
+				lig = nil
+				break
+			}
+			lig.runes = append(lig.runes, r)
+		}
+		if lig == nil {
+			continue
+		}
+
+		gBnd, gAdv, ok := face.indexBounds(lig.glyph)
+		if !ok {
+			continue
+		}
+		lig.char, lig.bounds = ra.character(gBnd, gAdv), gBnd
+		ligatures = append(ligatures, lig)
+	}
+	return ligatures
+}
+
+//draw draws the glyph of the ligature at the atlas position of its character
+func (lig *ligature) draw(ra *rasterizer, dst *image.Gray) error {
+	return ra.drawMask(dst, lig.char, lig.bounds, func(dot fixed.Point26_6) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+		return lig.face.indexGlyph(dot, lig.glyph)
+	})
+}
+
+//gsubLigature is a ligature of the GSUB table, by glyph index
+type gsubLigature struct {
+	components []uint16
+	glyph      uint16
+}
+
+//parseLigatures returns the ligatures of the lookups of the liga feature of a GSUB table,
+//for every script, in the order they apply. It returns nil if the table is malformed.
+func parseLigatures(gsub []byte) []gsubLigature {
+	t := &tableReader{b: gsub}
+	featureList := t.u16(6)
+	lookupList := t.u16(8)
+
+	//the lookups of the liga feature, applied in the order of the lookup list
+	seen := make(map[int]bool)
+	var lookups []int
+	features := t.u16(featureList)
+	for i := 0; i < features && !t.bad; i++ {
+		record := featureList + 2 + 6*i
+		if t.tag(record) != "liga" {
+			continue
+		}
+		feature := featureList + t.u16(record+4)
+		count := t.u16(feature + 2)
+		for j := 0; j < count && !t.bad; j++ {
+			if lookup := t.u16(feature + 4 + 2*j); !seen[lookup] {
+				seen[lookup] = true
+				lookups = append(lookups, lookup)
+			}
+		}
+	}
+	sort.Ints(lookups)
+
+	var ligatures []gsubLigature
+	for _, l := range lookups {
+		lookup := lookupList + t.u16(lookupList+2+2*l)
+		kind := t.u16(lookup)
+		subtables := t.u16(lookup + 4)
+		for s := 0; s < subtables && !t.bad; s++ {
+			subtable := lookup + t.u16(lookup+6+2*s)
+			//extension subtables point to the actual one with a 32 bit offset
+			if kind == 7 && t.u16(subtable+2) == 4 {
+				subtable += t.u32(subtable + 4)
+			} else if kind != 4 {
+				continue
+			}
+			ligatures = append(ligatures, parseLigatureSubst(t, subtable)...)
+		}
+	}
+
+	if t.bad {
+		return nil
+	}
+	return ligatures
+}
+
+//parseLigatureSubst returns the ligatures of the ligature substitution subtable at offset
+func parseLigatureSubst(t *tableReader, offset int) []gsubLigature {
+	if t.u16(offset) != 1 {
+		return nil
+	}
+	firsts := parseCoverage(t, offset+t.u16(offset+2))
+	sets := t.u16(offset + 4)
+
+	var ligatures []gsubLigature
+	for i := 0; i < sets && i < len(firsts) && !t.bad; i++ {
+		set := offset + t.u16(offset+6+2*i)
+		count := t.u16(set)
+		for j := 0; j < count && !t.bad; j++ {
+			lig := set + t.u16(set+2+2*j)
+			components := []uint16{firsts[i]}
+			for k := 1; k < t.u16(lig+2) && !t.bad; k++ {
+				components = append(components, uint16(t.u16(lig+2+2*k)))
+			}
+			ligatures = append(ligatures, gsubLigature{components: components, glyph: uint16(t.u16(lig))})
+		}
+	}
+	return ligatures
+}
+
+//parseCoverage returns the glyphs of the coverage table at offset, in the order of their coverage index
+func parseCoverage(t *tableReader, offset int) []uint16 {
+	var glyphs []uint16
+	count := t.u16(offset + 2)
+	switch t.u16(offset) {
+	case 1:
+		for i := 0; i < count && !t.bad; i++ {
+			glyphs = append(glyphs, uint16(t.u16(offset+4+2*i)))
+		}
+	case 2:
+		//ranges of consecutive glyphs, sorted by glyph and coverage index
+		for i := 0; i < count && !t.bad; i++ {
+			record := offset + 4 + 6*i
+			for g := t.u16(record); g <= t.u16(record+2) && !t.bad; g++ {
+				glyphs = append(glyphs, uint16(g))
+			}
+		}
+	}
+	return glyphs
+}
+
+//tableReader reads the big endian values of an OpenType table, remembering reads past its end
+type tableReader struct {
+	b   []byte
+	bad bool
+}
+
+func (t *tableReader) u16(offset int) int {
+	if offset < 0 || offset+2 > len(t.b) {
+		t.bad = true
+		return 0
+	}
+	return int(u16(t.b[offset:]))
+}
+
+func (t *tableReader) u32(offset int) int {
+	if offset < 0 || offset+4 > len(t.b) {
+		t.bad = true
+		return 0
+	}
+	return int(u32(t.b[offset:]))
+}
+
+func (t *tableReader) tag(offset int) string {
+	if offset < 0 || offset+4 > len(t.b) {
+		t.bad = true
+		return ""
+	}
+	return string(t.b[offset : offset+4])
+}
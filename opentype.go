@@ -0,0 +1,247 @@
+package glfont
+
+import (
+	"image"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+//outlines is a parsed font file, with TrueType glyf outlines or OpenType CFF ones
+type outlines interface {
+	//has reports whether the font has a glyph for r
+	has(r rune) bool
+	//newFace returns the face drawing the glyphs at size points
+	newFace(size, dpi float64, hinting font.Hinting) font.Face
+	//vadvance returns the vertical advance of r at ppem pixels per em
+	vadvance(ppem fixed.Int26_6, r rune) fixed.Int26_6
+}
+
+//parseOutlines parses a standalone font, TrueType first and OpenType for the fonts
+//with CFF outlines the truetype package does not support
+func parseOutlines(data []byte) (outlines, error) {
+	ttf, err := truetype.Parse(data)
+	if err == nil {
+		return ttfOutlines{ttf}, nil
+	}
+	otf, otfErr := sfnt.Parse(data)
+	if otfErr != nil {
+		return nil, err
+	}
+	return otfOutlines{otf}, nil
+}
+
+//ttfOutlines are the outlines of a font parsed by the truetype package
+type ttfOutlines struct {
+	ttf *truetype.Font
+}
+
+func (o ttfOutlines) has(r rune) bool {
+	return o.ttf.Index(r) != 0
+}
+
+func (o ttfOutlines) newFace(size, dpi float64, hinting font.Hinting) font.Face {
+	return truetype.NewFace(o.ttf, &truetype.Options{Size: size, DPI: dpi, Hinting: hinting})
+}
+
+func (o ttfOutlines) vadvance(ppem fixed.Int26_6, r rune) fixed.Int26_6 {
+	return fixed.Int26_6(o.ttf.VMetric(ppem, o.ttf.Index(r)).AdvanceHeight)
+}
+
+//otfOutlines are the outlines of an OpenType font parsed by the sfnt package
+type otfOutlines struct {
+	otf *sfnt.Font
+}
+
+func (o otfOutlines) has(r rune) bool {
+	var buf sfnt.Buffer
+	i, err := o.otf.GlyphIndex(&buf, r)
+	return err == nil && i != 0
+}
+
+func (o otfOutlines) newFace(size, dpi float64, hinting font.Hinting) font.Face {
+	return &otfFace{
+		otf:     o.otf,
+		ppem:    fixed.Int26_6(size * dpi / 72 * 64),
+		hinting: hinting,
+	}
+}
+
+//vadvance is the line height, the sfnt package does not read vertical metrics
+func (o otfOutlines) vadvance(ppem fixed.Int26_6, r rune) fixed.Int26_6 {
+	var buf sfnt.Buffer
+	metrics, _ := o.otf.Metrics(&buf, ppem, font.HintingNone)
+	return metrics.Ascent + metrics.Descent
+}
+
+//otfFace is a font.Face drawing the glyphs of an OpenType font at ppem, with the
+//outlines rasterized by the vector package. It is not safe for concurrent use.
+type otfFace struct {
+	otf     *sfnt.Font
+	ppem    fixed.Int26_6
+	hinting font.Hinting
+	buf     sfnt.Buffer
+	raster  vector.Rasterizer
+}
+
+func (f *otfFace) Close() error {
+	return nil
+}
+
+func (f *otfFace) Metrics() font.Metrics {
+	metrics, _ := f.otf.Metrics(&f.buf, f.ppem, f.hinting)
+	return metrics
+}
+
+func (f *otfFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	i0, _ := f.otf.GlyphIndex(&f.buf, r0)
+	i1, _ := f.otf.GlyphIndex(&f.buf, r1)
+	kern, err := f.otf.Kern(&f.buf, i0, i1, f.ppem, f.hinting)
+	if err != nil {
+		return 0
+	}
+	return kern
+}
+
+func (f *otfFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	i, err := f.otf.GlyphIndex(&f.buf, r)
+	if err != nil {
+		return 0, false
+	}
+	advance, err := f.otf.GlyphAdvance(&f.buf, i, f.ppem, f.hinting)
+	return advance, err == nil
+}
+
+func (f *otfFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	i, err := f.otf.GlyphIndex(&f.buf, r)
+	if err != nil {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	return f.indexBounds(i)
+}
+
+func (f *otfFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	i, err := f.otf.GlyphIndex(&f.buf, r)
+	if err != nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	return f.indexGlyph(dot, i)
+}
+
+//indexBounds is GlyphBounds for the glyph at index i of the font, which may have no rune
+func (f *otfFace) indexBounds(i sfnt.GlyphIndex) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	advance, err := f.otf.GlyphAdvance(&f.buf, i, f.ppem, f.hinting)
+	if err != nil {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	segments, ok := f.segments(i)
+	if !ok {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	return segmentBounds(segments), advance, true
+}
+
+//indexGlyph is Glyph for the glyph at index i of the font, which may have no rune
+func (f *otfFace) indexGlyph(dot fixed.Point26_6, i sfnt.GlyphIndex) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	advance, err := f.otf.GlyphAdvance(&f.buf, i, f.ppem, f.hinting)
+	if err != nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	segments, ok := f.segments(i)
+	if !ok {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+
+	//the whole pixels covered by the outline placed at dot
+	b := segmentBounds(segments)
+	dr := image.Rect(
+		(dot.X + b.Min.X).Floor(), (dot.Y + b.Min.Y).Floor(),
+		(dot.X + b.Max.X).Ceil(), (dot.Y + b.Max.Y).Ceil(),
+	)
+	mask := image.NewAlpha(image.Rect(0, 0, dr.Dx(), dr.Dy()))
+	if dr.Empty() {
+		return dr, mask, image.Point{}, advance, true
+	}
+
+	//the outline relative to the top left corner of the mask
+	ox := float32(dot.X)/64 - float32(dr.Min.X)
+	oy := float32(dot.Y)/64 - float32(dr.Min.Y)
+	px := func(p fixed.Point26_6) (float32, float32) {
+		return ox + float32(p.X)/64, oy + float32(p.Y)/64
+	}
+
+	f.raster.Reset(dr.Dx(), dr.Dy())
+	for _, seg := range segments {
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			f.raster.MoveTo(px(seg.Args[0]))
+		case sfnt.SegmentOpLineTo:
+			f.raster.LineTo(px(seg.Args[0]))
+		case sfnt.SegmentOpQuadTo:
+			bx, by := px(seg.Args[0])
+			cx, cy := px(seg.Args[1])
+			f.raster.QuadTo(bx, by, cx, cy)
+		case sfnt.SegmentOpCubeTo:
+			bx, by := px(seg.Args[0])
+			cx, cy := px(seg.Args[1])
+			dx, dy := px(seg.Args[2])
+			f.raster.CubeTo(bx, by, cx, cy, dx, dy)
+		}
+	}
+	f.raster.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+
+	return dr, mask, image.Point{}, advance, true
+}
+
+//segments returns the outline of the glyph at index i at the size of the face, with y increasing down
+func (f *otfFace) segments(i sfnt.GlyphIndex) ([]sfnt.Segment, bool) {
+	segments, err := f.otf.LoadGlyph(&f.buf, i, f.ppem, nil)
+	if err != nil {
+		return nil, false
+	}
+	return segments, true
+}
+
+//segmentBounds returns the bounds of the points of segments, rounded out to whole pixels
+//like the ones of the truetype package
+func segmentBounds(segments []sfnt.Segment) fixed.Rectangle26_6 {
+	if len(segments) == 0 {
+		return fixed.Rectangle26_6{}
+	}
+
+	b := fixed.Rectangle26_6{Min: segments[0].Args[0], Max: segments[0].Args[0]}
+	for _, seg := range segments {
+		n := 1
+		switch seg.Op {
+		case sfnt.SegmentOpQuadTo:
+			n = 2
+		case sfnt.SegmentOpCubeTo:
+			n = 3
+		}
+		for _, p := range seg.Args[:n] {
+			b.Min.X, b.Min.Y = minFixed(b.Min.X, p.X), minFixed(b.Min.Y, p.Y)
+			b.Max.X, b.Max.Y = maxFixed(b.Max.X, p.X), maxFixed(b.Max.Y, p.Y)
+		}
+	}
+
+	b.Min.X, b.Min.Y = fixed.I(b.Min.X.Floor()), fixed.I(b.Min.Y.Floor())
+	b.Max.X, b.Max.Y = fixed.I(b.Max.X.Ceil()), fixed.I(b.Max.Y.Ceil())
+	return b
+}
+
+func minFixed(a, b fixed.Int26_6) fixed.Int26_6 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFixed(a, b fixed.Int26_6) fixed.Int26_6 {
+	if a > b {
+		return a
+	}
+	return b
+}
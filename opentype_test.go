@@ -0,0 +1,60 @@
+package glfont
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestLoadCFFFont(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/CFFTest.otf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := parseFont(data, 0)
+	if err != nil {
+		t.Fatalf("parseFont: %v", err)
+	}
+	if _, ok := p.glyphs.(otfOutlines); !ok {
+		t.Fatalf("the font is parsed as %T, not with CFF outlines", p.glyphs)
+	}
+
+	f, atlas, err := buildFont(data, Options{Scale: 20, Low: '0', High: '1', Runes: []rune{'Q'}}, 8192)
+	if err != nil {
+		t.Fatalf("buildFont: %v", err)
+	}
+
+	//the advances of the font are 600, 400 and 1000 units of a 1000 units em
+	for _, glyph := range []struct {
+		r       rune
+		advance float32
+	}{
+		{'0', 12},
+		{'1', 8},
+		{'Q', 20},
+	} {
+		char, ok := f.fontChar[glyph.r]
+		if !ok {
+			t.Errorf("%q is not loaded", glyph.r)
+			continue
+		}
+		if got := f.Advance(1, glyph.r); got != glyph.advance {
+			t.Errorf("%q advances %g, want %g", glyph.r, got, glyph.advance)
+		}
+		if char.empty() {
+			t.Errorf("%q has no ink", glyph.r)
+			continue
+		}
+
+		ink := 0
+		for y := char.y; y < char.y+char.height; y++ {
+			for x := char.x; x < char.x+char.width; x++ {
+				if atlas.GrayAt(x, y).Y > 0 {
+					ink++
+				}
+			}
+		}
+		if ink == 0 {
+			t.Errorf("%q is drawn blank in the atlas", glyph.r)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package glfont
+
+// skyline packs rectangles into an area by keeping track of the top edge
+// of the rectangles placed so far, and placing each new one as low as possible.
+type skyline struct {
+	width, height int
+	margin        int
+	nodes         []skylineNode
+}
+
+// skylineNode is a horizontal segment of the skyline.
+type skylineNode struct {
+	x, y, width int
+}
+
+func newSkyline(width, height, margin int) *skyline {
+	return &skyline{
+		width:  width,
+		height: height,
+		margin: margin,
+		nodes:  []skylineNode{{x: margin, y: margin, width: width - margin}},
+	}
+}
+
+// place assigns an atlas position to char and reports whether it fit.
+func (s *skyline) place(char *character) bool {
+	w := char.width + s.margin
+	h := char.height + s.margin
+
+	best, bestX, bestY := -1, 0, 0
+	for i := range s.nodes {
+		y, ok := s.fit(i, w, h)
+		if ok && (best < 0 || y < bestY) {
+			best, bestX, bestY = i, s.nodes[i].x, y
+		}
+	}
+	if best < 0 {
+		return false
+	}
+
+	char.x = bestX
+	char.y = bestY
+	s.add(best, skylineNode{x: bestX, y: bestY + h, width: w})
+	return true
+}
+
+// fit returns the height at which a w by h rectangle would rest when its left edge
+// is on node i, and whether it fits in the area there. w and h include the margin
+// kept to the right of and below the rectangle.
+func (s *skyline) fit(i, w, h int) (int, bool) {
+	y := 0
+	for left := w; left > 0; i++ {
+		if i == len(s.nodes) {
+			return 0, false
+		}
+		if s.nodes[i].y > y {
+			y = s.nodes[i].y
+		}
+		left -= s.nodes[i].width
+	}
+	return y, y+h <= s.height
+}
+
+// add inserts node at index i and shrinks or removes the nodes it covers.
+func (s *skyline) add(i int, node skylineNode) {
+	s.nodes = append(s.nodes, skylineNode{})
+	copy(s.nodes[i+1:], s.nodes[i:])
+	s.nodes[i] = node
+
+	for j := i + 1; j < len(s.nodes); {
+		end := node.x + node.width
+		if s.nodes[j].x >= end {
+			break
+		}
+		shrink := end - s.nodes[j].x
+		if s.nodes[j].width > shrink {
+			s.nodes[j].x += shrink
+			s.nodes[j].width -= shrink
+			break
+		}
+		s.nodes = append(s.nodes[:j], s.nodes[j+1:]...)
+	}
+
+	//merge neighbours at the same height
+	for j := 0; j+1 < len(s.nodes); {
+		if s.nodes[j].y == s.nodes[j+1].y {
+			s.nodes[j].width += s.nodes[j+1].width
+			s.nodes = append(s.nodes[:j+1], s.nodes[j+2:]...)
+			continue
+		}
+		j++
+	}
+}
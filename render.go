@@ -0,0 +1,396 @@
+package glfont
+
+import (
+	"math"
+
+	"github.com/go-gl/gl/all-core/gl"
+)
+
+//appendText appends the quads of text drawn with its first baseline at x, y to coords
+func (f *Font) appendText(coords []point, x, y float32, scale float32, text []rune) []point {
+	coords, _ = f.appendTextBounds(coords, x, y, scale, text)
+	return coords
+}
+
+//appendTextBounds is like appendText, also returning the bounds of the lines of text
+func (f *Font) appendTextBounds(coords []point, x, y float32, scale float32, text []rune) ([]point, Rect) {
+	var runs []textRun
+
+	// Iterate through all characters in string
+	width, lines := f.layout(scale, text, func(_ int, ch *character, src *Font, gx, gy float32) {
+		coords = f.appendGlyph(coords, src, ch, x+gx, y+gy, scale)
+		runs = f.extendRun(runs, src, ch, x+gx, y+gy, scale)
+	})
+
+	return f.appendDecorations(coords, runs, scale), f.textBounds(x, y, scale, width, lines)
+}
+
+//textBounds returns the bounds of lines of text as wide as width drawn with their first baseline at x, y
+func (f *Font) textBounds(x, y float32, scale float32, width float32, lines int) Rect {
+	across := float32(lines-1) * f.lineAdvance(scale)
+
+	//the lines of vertical text are columns centered on x, to the left of each other
+	if f.dir == TopToBottom {
+		r := Rect{X: x - across - f.lineHeight*scale/2, Y: y, W: across + f.lineHeight*scale, H: width}
+		if f.yAxis == YUp {
+			r.Y = y - width
+		}
+		return r
+	}
+
+	r := Rect{X: x, Y: y - f.ascent*scale, W: width, H: across + f.lineHeight*scale}
+	if f.dir == RightToLeft {
+		r.X = x - width
+	}
+	if f.yAxis == YUp {
+		r.Y = y - across - f.descent*scale
+	}
+	return r
+}
+
+//appendGlyph appends the quad of ch with its origin on the baseline at x, y
+func (f *Font) appendGlyph(coords []point, src *Font, ch *character, x, y float32, scale float32) []point {
+	if ch.empty() {
+		return coords
+	}
+
+	//calculate position and size for current rune
+	gs := src.glyphScale(scale)
+	xpos := x + float32(ch.bearingH)*gs
+	ypos := y - f.down(float32(ch.height-ch.bearingV)*gs)
+	if f.pixelSnap {
+		xpos = float32(math.Round(float64(xpos)))
+		ypos = float32(math.Round(float64(ypos)))
+	}
+
+	top, bottom := f.color, f.color
+	if f.gradient.enabled {
+		top, bottom = f.gradient.top, f.gradient.bottom
+		//place the glyph in the gradient spanning the line
+		if f.gradient.perLine {
+			lineTop := y - f.down(f.ascent*scale)
+			lineHeight := (f.ascent + f.descent) * scale
+			top = f.gradient.at(f.down(ypos-lineTop) / lineHeight)
+			bottom = f.gradient.at(f.down(ypos+f.down(float32(ch.height)*gs)-lineTop) / lineHeight)
+		}
+	}
+
+	start := len(coords)
+	coords = f.appendQuad(coords, src, ch, xpos, ypos, gs, top, bottom)
+
+	//faux italic shears the quad around the baseline
+	if f.fauxItalic != 0 {
+		for i := start; i < len(coords); i++ {
+			coords[i][0] += f.fauxItalic * f.down(y-coords[i][1])
+		}
+	}
+
+	return coords
+}
+
+//appendQuad appends the two triangles drawing ch from the atlas of src with its top left corner at xpos, ypos,
+//colored from top to bottom, which is at a smaller y than the top unless the font is YUp
+func (f *Font) appendQuad(coords []point, src *Font, ch *character, xpos, ypos float32, scale float32, top, bottom color) []point {
+	w := float32(ch.width) * scale
+	h := f.down(float32(ch.height) * scale)
+
+	//set quad positions
+	var x1 = xpos
+	var x2 = xpos + w
+	var y1 = ypos
+	var y2 = ypos + h
+
+	//bake the colors in the vertices
+	t, b := top, bottom
+
+	coords = append(coords, point{x1, y1, float32(ch.x) / src.atlasWidth, float32(ch.y) / src.atlasHeight, t.r, t.g, t.b, t.a})
+	coords = append(coords, point{x2, y1, float32(ch.x+ch.width) / src.atlasWidth, float32(ch.y) / src.atlasHeight, t.r, t.g, t.b, t.a})
+	coords = append(coords, point{x1, y2, float32(ch.x) / src.atlasWidth, float32(ch.y+ch.height) / src.atlasHeight, b.r, b.g, b.b, b.a})
+	coords = append(coords, point{x2, y1, float32(ch.x+ch.width) / src.atlasWidth, float32(ch.y) / src.atlasHeight, t.r, t.g, t.b, t.a})
+	coords = append(coords, point{x1, y2, float32(ch.x) / src.atlasWidth, float32(ch.y+ch.height) / src.atlasHeight, b.r, b.g, b.b, b.a})
+	coords = append(coords, point{x2, y2, float32(ch.x+ch.width) / src.atlasWidth, float32(ch.y+ch.height) / src.atlasHeight, b.r, b.g, b.b, b.a})
+
+	//remember which atlas to draw the quad with
+	f.quadFonts = append(f.quadFonts, src)
+
+	return coords
+}
+
+// Begin starts a batch: the text drawn until End is accumulated and rendered
+// with a single draw call by End. Changing the shadow or outline during a batch
+// renders the text accumulated so far with the previous ones.
+func (f *Font) Begin() {
+	f.batching = true
+	f.coords = f.coords[:0]
+	f.quadFonts = f.quadFonts[:0]
+	f.backdrop = f.backdrop[:0]
+}
+
+// End renders the text drawn since Begin.
+func (f *Font) End() error {
+	f.batching = false
+	return f.render(f.coords)
+}
+
+//flush renders the text batched so far, if any
+func (f *Font) flush() {
+	if f.batching && (len(f.coords) > 0 || len(f.backdrop) > 0) {
+		f.render(f.coords)
+	}
+}
+
+//scratch returns the slice to append quads drawn at scale to: the pending batch between
+//Begin and End, an empty reusable slice otherwise
+func (f *Font) scratch(scale float32) []point {
+	if f.batching {
+		if f.breaksBatch(scale) {
+			f.flush()
+		}
+		f.drawScale = scale
+		return f.coords
+	}
+	f.drawScale = scale
+	f.quadFonts = f.quadFonts[:0]
+	f.backdrop = f.backdrop[:0]
+	return f.coords[:0]
+}
+
+//breaksBatch reports whether text drawn at scale has to be drawn apart from the pending
+//batch, whose shadow, outline and faux bold offsets depend on the single scale it is drawn at
+func (f *Font) breaksBatch(scale float32) bool {
+	scaled := f.shadow.enabled() || f.outline.enabled() || f.fauxBold.strength != 0
+	return scaled && scale != f.drawScale
+}
+
+//boldOffset returns how many pixels faux bold widens the glyphs of text drawn at scale
+func (f *Font) boldOffset(scale float32) float32 {
+	return f.fauxBold.strength * scale
+}
+
+//draw renders the quads in coords, or keeps them for End during a batch
+func (f *Font) draw(coords []point) error {
+	if f.batching {
+		f.coords = coords
+		return nil
+	}
+	return f.render(coords)
+}
+
+//atlasRun is a range of vertices drawn with the atlas of a font
+type atlasRun struct {
+	font         *Font
+	start, count int32
+}
+
+//groupByAtlas reorders the quads in coords after prefix so that the ones drawn from the same
+//atlas are contiguous, the font's own first, and returns the ranges of each atlas
+func (f *Font) groupByAtlas(prefix, coords []point) ([]point, []atlasRun) {
+	var runs []atlasRun
+	for _, src := range f.quadFonts {
+		found := false
+		for _, run := range runs {
+			found = found || run.font == src
+		}
+		if !found {
+			runs = append(runs, atlasRun{font: src})
+		}
+	}
+	if len(runs) == 1 && len(prefix) == 0 {
+		runs[0].count = int32(len(coords))
+		return coords, runs
+	}
+
+	grouped := append(f.grouped[:0], prefix...)
+	for i := range runs {
+		runs[i].start = int32(len(grouped))
+		for q, src := range f.quadFonts {
+			if src == runs[i].font {
+				grouped = append(grouped, coords[q*6:q*6+6]...)
+			}
+		}
+		runs[i].count = int32(len(grouped)) - runs[i].start
+	}
+	f.grouped = grouped
+
+	return grouped, runs
+}
+
+//render draws the quads in coords with the font texture and color
+func (f *Font) render(coords []point) error {
+	if len(coords) == 0 && len(f.backdrop) == 0 {
+		return nil
+	}
+
+	if f.restoreState {
+		defer saveState(f.manageBlend).restore()
+	}
+
+	f.setupDraw()
+	gl.BindVertexArray(f.vao)
+
+	//glyphs from fallback fonts are drawn with their own atlas, after the backgrounds
+	vertices, runs := f.groupByAtlas(f.backdrop, coords)
+	uploadPending(runs)
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, f.vbo)
+
+	//only reallocate the buffer when the quads do not fit in it
+	size := len(vertices) * pointSize
+	if size > f.vboSize {
+		f.vboSize = 2 * f.vboSize
+		if size > f.vboSize {
+			f.vboSize = size
+		}
+		gl.BufferData(gl.ARRAY_BUFFER, f.vboSize, nil, gl.DYNAMIC_DRAW)
+	}
+	gl.BufferSubData(gl.ARRAY_BUFFER, 0, size, gl.Ptr(vertices))
+
+	f.drawPasses(runs, int32(len(f.backdrop)), f.drawScale, 0, 0)
+
+	//keep the slices around to avoid allocating on the next call
+	f.coords = coords[:0]
+	f.quadFonts = f.quadFonts[:0]
+	f.backdrop = f.backdrop[:0]
+
+	if !f.restoreState {
+		f.unbindDraw()
+	}
+
+	return nil
+}
+
+//setupDraw enables blending and activates the program of the font with its current settings
+func (f *Font) setupDraw() {
+	//setup blending mode, unless the caller manages it
+	if f.manageBlend {
+		gl.Enable(gl.BLEND)
+		if f.blendMode == Premultiplied {
+			gl.BlendFunc(gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
+		} else {
+			gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+		}
+	}
+
+	// Activate corresponding render state
+	gl.UseProgram(f.program)
+	if f.blendMode == Premultiplied {
+		gl.Uniform1i(f.premultiplyUniform, 1)
+	} else {
+		gl.Uniform1i(f.premultiplyUniform, 0)
+	}
+	//map the quads with the caller transform, the projection or the window resolution
+	if f.transform != nil {
+		gl.Uniform1i(f.useTransform, 1)
+		gl.UniformMatrix4fv(f.transformUniform, 1, false, &f.transform[0])
+	} else if f.projection != nil {
+		gl.Uniform1i(f.useTransform, 1)
+		gl.UniformMatrix4fv(f.transformUniform, 1, false, &f.projection[0])
+	} else {
+		gl.Uniform1i(f.useTransform, 0)
+	}
+
+	gl.Uniform1f(f.opacityUniform, f.opacity)
+	gl.Uniform1f(f.edgeUniform, 0.5)
+	gl.Uniform1f(f.gammaUniform, 1)
+
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+//unbindDraw leaves the state changed by setupDraw unbound and blending disabled
+func (f *Font) unbindDraw() {
+	gl.BindVertexArray(0)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	gl.UseProgram(0)
+	if f.manageBlend {
+		gl.Disable(gl.BLEND)
+	}
+}
+
+//uploadPending copies the glyphs added to the dynamic atlases of runs since they were last drawn
+func uploadPending(runs []atlasRun) {
+	for _, run := range runs {
+		if run.font.dynamic != nil {
+			gl.BindTexture(gl.TEXTURE_2D, run.font.textureID)
+			run.font.faceMu.Lock()
+			run.font.dynamic.upload()
+			run.font.faceMu.Unlock()
+		}
+	}
+}
+
+//drawPasses draws the vertices of the bound vertex array: the first backdrop ones as backgrounds,
+//then runs with the shadow, outline and faux bold of text drawn at scale, all moved by ox, oy
+func (f *Font) drawPasses(runs []atlasRun, backdrop int32, scale float32, ox, oy float32) {
+	//faux bold widens the glyphs to the right by bold pixels
+	bold := f.boldOffset(scale)
+	drawRuns := func(dx, dy float32) {
+		dx, dy = dx+ox, dy+oy
+		for _, run := range runs {
+			gl.BindTexture(gl.TEXTURE_2D, run.font.textureID)
+			//tell the shader how to read the atlas
+			if run.font.sdf {
+				gl.Uniform1i(f.sdfUniform, 1)
+			} else {
+				gl.Uniform1i(f.sdfUniform, 0)
+			}
+			gl.Uniform1f(f.gammaUniform, run.font.gamma)
+			if bold <= 0 {
+				gl.Uniform2f(f.offsetUniform, dx, dy)
+				gl.DrawArrays(gl.TRIANGLES, run.start, run.count)
+				continue
+			}
+
+			//distance fields are thickened by moving their edge outwards, centered on the widened glyph
+			if run.font.sdf {
+				gl.Uniform1f(f.edgeUniform, 0.5-run.font.boldEdgeShift(f.fauxBold.strength))
+				gl.Uniform2f(f.offsetUniform, dx+bold/2, dy)
+				gl.DrawArrays(gl.TRIANGLES, run.start, run.count)
+				gl.Uniform1f(f.edgeUniform, 0.5)
+				continue
+			}
+
+			//coverage is thickened by drawing copies of the glyphs at most a pixel apart
+			steps := int(math.Ceil(float64(bold)))
+			for i := 0; i <= steps; i++ {
+				gl.Uniform2f(f.offsetUniform, dx+bold*float32(i)/float32(steps), dy)
+				gl.DrawArrays(gl.TRIANGLES, run.start, run.count)
+			}
+		}
+	}
+
+	//draw the backgrounds beneath everything else
+	if backdrop > 0 {
+		gl.BindTexture(gl.TEXTURE_2D, f.textureID)
+		if f.sdf {
+			gl.Uniform1i(f.sdfUniform, 1)
+		} else {
+			gl.Uniform1i(f.sdfUniform, 0)
+		}
+		gl.Uniform2f(f.offsetUniform, ox, oy)
+		gl.Uniform1i(f.useVertexColor, 1)
+		gl.DrawArrays(gl.TRIANGLES, 0, backdrop)
+	}
+
+	//the shadow and outline are drawn in their own color
+	gl.Uniform1i(f.useVertexColor, 0)
+
+	//draw the shadow beneath the text
+	if f.shadow.enabled() {
+		gl.Uniform4f(f.colorUniform, f.shadow.color.r, f.shadow.color.g, f.shadow.color.b, f.shadow.color.a)
+		drawRuns(f.shadow.x*scale, f.shadow.y*scale)
+	}
+
+	//draw the outline as copies of the text in a ring beneath it
+	if f.outline.enabled() {
+		radius := f.outline.thickness * scale
+		gl.Uniform4f(f.colorUniform, f.outline.color.r, f.outline.color.g, f.outline.color.b, f.outline.color.a)
+		for i := 0; i < outlineSteps; i++ {
+			sin, cos := math.Sincos(2 * math.Pi * float64(i) / outlineSteps)
+			drawRuns(float32(cos)*radius, float32(sin)*radius)
+		}
+	}
+
+	//the text is drawn with the colors baked in its vertices
+	gl.Uniform1i(f.useVertexColor, 1)
+	drawRuns(0, 0)
+}
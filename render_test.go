@@ -0,0 +1,64 @@
+package glfont
+
+import (
+	imgcolor "image/color"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestFauxBoldBreaksBatchOnScale(t *testing.T) {
+	f := &Font{}
+	f.Begin()
+	f.scratch(1)
+
+	if f.breaksBatch(2) {
+		t.Fatal("plain text at another scale breaks the batch")
+	}
+
+	f.fauxBold.strength = 1.5
+	if f.breaksBatch(1) {
+		t.Error("faux bold text at the same scale breaks the batch")
+	}
+	if !f.breaksBatch(2) {
+		t.Error("faux bold text at another scale joins the batch, drawn with the offset of the wrong scale")
+	}
+
+	for _, scale := range []float32{0.5, 1, 2, 3} {
+		if got, want := f.boldOffset(scale), 1.5*scale; got != want {
+			t.Errorf("bold offset at scale %g = %g, want %g", scale, got, want)
+		}
+	}
+}
+
+func TestSpaceAddsNoQuad(t *testing.T) {
+	f := loadTestFont(t, Options{Scale: 20})
+
+	if space := f.fontChar[' ']; !space.empty() {
+		t.Fatalf("space is loaded %dx%d", space.width, space.height)
+	}
+	if got := len(f.appendText(nil, 0, 0, 1, []rune(" "))); got != 0 {
+		t.Errorf("a space is drawn with %d vertices", got)
+	}
+	if got := len(f.appendText(nil, 0, 0, 1, []rune("a b"))); got != 12 {
+		t.Errorf("a b is drawn with %d vertices, want 12", got)
+	}
+}
+
+func TestRenderToImageLargeScale(t *testing.T) {
+	img, err := RenderToImage(goregular.TTF, 256, imgcolor.White, "Hi")
+	if err != nil {
+		t.Fatalf("RenderToImage at scale 256: %v", err)
+	}
+	if img.Bounds().Dy() < 256 {
+		t.Errorf("image is %v, want it at least 256 pixels high", img.Bounds())
+	}
+
+	ink := false
+	for i := 3; i < len(img.Pix) && !ink; i += 4 {
+		ink = img.Pix[i] != 0
+	}
+	if !ink {
+		t.Error("nothing is drawn")
+	}
+}
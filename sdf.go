@@ -0,0 +1,89 @@
+package glfont
+
+import (
+	"image"
+	"math"
+)
+
+// offset is the vector from a pixel to the nearest pixel of the other kind.
+type offset struct {
+	dx, dy int
+}
+
+func (o offset) dist2() int {
+	return o.dx*o.dx + o.dy*o.dy
+}
+
+// far marks pixels whose nearest pixel of the other kind is not known yet.
+var far = offset{1 << 14, 1 << 14}
+
+// distanceField replaces the glyph coverage inside r of img with a signed distance field.
+// Edges map to 128, and the value moves to 255 inside and 0 outside over spread pixels.
+func distanceField(img *image.Gray, r image.Rectangle, spread int) {
+	w, h := r.Dx(), r.Dy()
+	if w == 0 || h == 0 {
+		return
+	}
+
+	inside := make([]offset, w*h)
+	outside := make([]offset, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if img.GrayAt(r.Min.X+x, r.Min.Y+y).Y >= 128 {
+				outside[y*w+x] = far
+			} else {
+				inside[y*w+x] = far
+			}
+		}
+	}
+	sweep(inside, w, h)
+	sweep(outside, w, h)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			//positive inside the glyph, negative outside
+			d := math.Sqrt(float64(outside[y*w+x].dist2())) - math.Sqrt(float64(inside[y*w+x].dist2()))
+			v := 128 + d*127/float64(spread)
+			img.Pix[img.PixOffset(r.Min.X+x, r.Min.Y+y)] = uint8(math.Max(0, math.Min(255, v)))
+		}
+	}
+}
+
+// sweep propagates the nearest pixel offsets over the grid with the 8SSEDT algorithm.
+func sweep(g []offset, w, h int) {
+	compare := func(x, y, ox, oy int) {
+		if x+ox < 0 || x+ox >= w || y+oy < 0 || y+oy >= h {
+			return
+		}
+		other := g[(y+oy)*w+x+ox]
+		other.dx += ox
+		other.dy += oy
+		if other.dist2() < g[y*w+x].dist2() {
+			g[y*w+x] = other
+		}
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			compare(x, y, -1, 0)
+			compare(x, y, 0, -1)
+			compare(x, y, -1, -1)
+			compare(x, y, 1, -1)
+		}
+		for x := w - 1; x >= 0; x-- {
+			compare(x, y, 1, 0)
+		}
+	}
+
+	for y := h - 1; y >= 0; y-- {
+		for x := w - 1; x >= 0; x-- {
+			compare(x, y, 1, 0)
+			compare(x, y, 0, 1)
+			compare(x, y, -1, 1)
+			compare(x, y, 1, 1)
+		}
+		for x := 0; x < w; x++ {
+			compare(x, y, -1, 0)
+		}
+	}
+}
@@ -4,9 +4,58 @@ import (
 	"github.com/go-gl/gl/all-core/gl"
 
 	"fmt"
+	"math"
 	"strings"
 )
 
+//shaderSources holds the null terminated sources of a vertex and a fragment shader
+type shaderSources struct {
+	vertex, fragment string
+}
+
+var defaultShaders = shaderSources{vertex: vertexFontShader, fragment: fragmentFontShader}
+
+//terminate returns source null terminated, as expected by GL
+func terminate(source string) string {
+	if strings.HasSuffix(source, "\x00") {
+		return source
+	}
+	return source + "\x00"
+}
+
+// DefaultVertexShader and DefaultFragmentShader are the sources of the shaders text is drawn
+// with, a starting point for the custom shaders of LoadFontShaders. They are compiled after a
+// #version line, and should handle the GLSL versions the program may be compiled for.
+//
+// The vertex shader receives, per vertex:
+//   - vert, a vec2: the position in pixels, or in the units of the transform
+//   - vertTexCoord, a vec2: the position in the atlas, from 0 to 1
+//   - vertColor, a vec4: the color of the text at the vertex
+//
+// and the uniforms:
+//   - resolution, a vec2: the window size in pixels
+//   - offset, a vec2: added to vert, for the shadow, outline and faux bold copies
+//   - transform, a mat4, and useTransform, a bool: the matrix mapping vert to clip space
+//     when set, instead of resolution
+//
+// The default vertex shader passes vertTexCoord and vertColor to the fragment shader as
+// fragTexCoord and fragColor. The fragment shader receives the uniforms:
+//   - tex, a sampler2D: the atlas, with the coverage or distance field in its red channel,
+//     or in alpha with AtlasOptions.RGBA
+//   - textColor, a vec4, and useVertexColor, a bool: textColor is the color of the shadow
+//     and outline, drawn when useVertexColor is false, with its alpha multiplied by the
+//     alpha of fragColor
+//   - opacity, a float: multiplies the alpha of everything drawn
+//   - sdf, a bool, and edge, a float: the atlas holds distance fields, drawn up to edge
+//   - premultiply, a bool: output colors multiplied by their alpha
+//   - gamma, a float: the coverage is raised to 1/gamma
+//
+// Uniforms and inputs a custom shader does not declare are ignored.
+var (
+	DefaultVertexShader   = strings.TrimSuffix(vertexFontShader, "\x00")
+	DefaultFragmentShader = strings.TrimSuffix(fragmentFontShader, "\x00")
+)
+
 //newProgram links the frag and vertex shader programs
 func newProgram(GLSLVersion uint, vertexShaderSource, fragmentShaderSource string) (uint32, error) {
 	vertexShaderSource = fmt.Sprintf("#version %d\n", GLSLVersion) + vertexShaderSource
@@ -17,11 +66,15 @@ func newProgram(GLSLVersion uint, vertexShaderSource, fragmentShaderSource strin
 		return 0, err
 	}
 
+	defer gl.DeleteShader(vertexShader)
+
 	fragmentShader, err := compileShader(fragmentShaderSource, gl.FRAGMENT_SHADER)
 	if err != nil {
 		return 0, err
 	}
+	defer gl.DeleteShader(fragmentShader)
 
+	//the shaders are only deleted once the program they are attached to is
 	program := gl.CreateProgram()
 
 	gl.AttachShader(program, vertexShader)
@@ -36,16 +89,34 @@ func newProgram(GLSLVersion uint, vertexShaderSource, fragmentShaderSource strin
 
 		log := strings.Repeat("\x00", int(logLength+1))
 		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
+		gl.DeleteProgram(program)
 
 		return 0, fmt.Errorf("failed to link program: %v", log)
 	}
 
-	gl.DeleteShader(vertexShader)
-	gl.DeleteShader(fragmentShader)
-
 	return program, nil
 }
 
+//lookupLocations resolves the uniforms and attributes of the font shader program
+func lookupLocations(program uint32) locations {
+	return locations{
+		resolutionUniform:  gl.GetUniformLocation(program, gl.Str("resolution\x00")),
+		colorUniform:       gl.GetUniformLocation(program, gl.Str("textColor\x00")),
+		transformUniform:   gl.GetUniformLocation(program, gl.Str("transform\x00")),
+		useTransform:       gl.GetUniformLocation(program, gl.Str("useTransform\x00")),
+		offsetUniform:      gl.GetUniformLocation(program, gl.Str("offset\x00")),
+		sdfUniform:         gl.GetUniformLocation(program, gl.Str("sdf\x00")),
+		edgeUniform:        gl.GetUniformLocation(program, gl.Str("edge\x00")),
+		premultiplyUniform: gl.GetUniformLocation(program, gl.Str("premultiply\x00")),
+		gammaUniform:       gl.GetUniformLocation(program, gl.Str("gamma\x00")),
+		useVertexColor:     gl.GetUniformLocation(program, gl.Str("useVertexColor\x00")),
+		opacityUniform:     gl.GetUniformLocation(program, gl.Str("opacity\x00")),
+		vertAttrib:         uint32(gl.GetAttribLocation(program, gl.Str("vert\x00"))),
+		texCoordAttrib:     uint32(gl.GetAttribLocation(program, gl.Str("vertTexCoord\x00"))),
+		colorAttrib:        uint32(gl.GetAttribLocation(program, gl.Str("vertColor\x00"))),
+	}
+}
+
 //compileShader compiles the shader program
 func compileShader(source string, shaderType uint32) (uint32, error) {
 	shader := gl.CreateShader(shaderType)
@@ -63,6 +134,7 @@ func compileShader(source string, shaderType uint32) (uint32, error) {
 
 		log := strings.Repeat("\x00", int(logLength+1))
 		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
+		gl.DeleteShader(shader)
 
 		return 0, fmt.Errorf("failed to compile %v: %v", source, log)
 	}
@@ -70,6 +142,15 @@ func compileShader(source string, shaderType uint32) (uint32, error) {
 	return shader, nil
 }
 
+//gammaCorrection is the line of the fragment shader correcting the coverage with the gamma
+//uniform, computed on the CPU by correctCoverage
+const gammaCorrection = "coverage = pow(coverage, 1.0 / gamma);"
+
+//correctCoverage returns coverage, from 0 to 1, corrected like gammaCorrection does in the shader
+func correctCoverage(coverage, gamma float32) float32 {
+	return float32(math.Pow(float64(coverage), 1/float64(gamma)))
+}
+
 var fragmentFontShader = `
 #if __VERSION__ >= 130
 #define COMPAT_VARYING in
@@ -85,14 +166,49 @@ out vec4 FragColor;
 #endif
 
 COMPAT_VARYING vec2 fragTexCoord;
+COMPAT_VARYING vec4 fragColor;
 
 uniform sampler2D tex;
 uniform vec4 textColor;
 
+//the text is drawn with the color of its vertices instead of textColor
+uniform bool useVertexColor;
+
+//multiplies the alpha of the text
+uniform float opacity;
+
+//the atlas holds signed distance fields, with edges at 0.5
+uniform bool sdf;
+
+//distance field value drawn as the edge, lowered to thicken the glyphs
+uniform float edge;
+
+//output the color premultiplied by its alpha
+uniform bool premultiply;
+
+//gamma the coverage is corrected with, 1.0 for none
+uniform float gamma;
+
 void main()
 {
-    vec4 sampled = vec4(1.0, 1.0, 1.0, COMPAT_TEXTURE(tex, fragTexCoord).r);
-    COMPAT_FRAGCOLOR = min(textColor, vec4(1.0, 1.0, 1.0, 1.0)) * sampled;
+    // the glyph coverage is in the red channel of a GL_RED atlas, whose alpha
+    // reads as 1.0, or in the alpha of the white texels of an RGBA one
+    vec4 texel = COMPAT_TEXTURE(tex, fragTexCoord);
+    float coverage = min(texel.r, texel.a);
+    if (sdf) {
+        float width = fwidth(coverage);
+        coverage = smoothstep(edge - width, edge + width, coverage);
+    }
+    // blend the edges as if in linear light against an sRGB framebuffer
+    ` + gammaCorrection + `
+    vec4 sampled = vec4(1.0, 1.0, 1.0, coverage);
+    // the shadow and outline fade with the alpha of the text drawn above them
+    vec4 color = useVertexColor ? fragColor : vec4(textColor.rgb, textColor.a * fragColor.a);
+    vec4 result = min(color, vec4(1.0, 1.0, 1.0, 1.0)) * sampled * vec4(1.0, 1.0, 1.0, opacity);
+    if (premultiply) {
+        result.rgb *= result.a;
+    }
+    COMPAT_FRAGCOLOR = result;
 }` + "\x00"
 
 var vertexFontShader = `
@@ -112,15 +228,35 @@ COMPAT_ATTRIBUTE vec2 vert;
 //pass through to fragTexCoord
 COMPAT_ATTRIBUTE vec2 vertTexCoord;
 
+//pass through to fragColor
+COMPAT_ATTRIBUTE vec4 vertColor;
+
 //window res
 uniform vec2 resolution;
 
+//offset of the quads in pixels, used for the shadow
+uniform vec2 offset;
+
+//caller supplied transform, used instead of the resolution when useTransform is set
+uniform mat4 transform;
+uniform bool useTransform;
+
 //pass to frag
 COMPAT_VARYING vec2 fragTexCoord;
+COMPAT_VARYING vec4 fragColor;
 
 void main() {
+   fragTexCoord = vertTexCoord;
+   fragColor = vertColor;
+
+   if (useTransform) {
+      gl_Position = transform * vec4(vert + offset, 0, 1);
+      return;
+   }
+
+   // pixels are y-down, with the origin at the top left of the window
    // convert the rectangle from pixels to 0.0 to 1.0
-   vec2 zeroToOne = vert / resolution;
+   vec2 zeroToOne = (vert + offset) / resolution;
 
    // convert from 0->1 to 0->2
    vec2 zeroToTwo = zeroToOne * 2.0;
@@ -128,7 +264,5 @@ void main() {
    // convert from 0->2 to -1->+1 (clipspace)
    vec2 clipSpace = zeroToTwo - 1.0;
 
-   fragTexCoord = vertTexCoord;
-
    gl_Position = vec4(clipSpace * vec2(1, -1), 0, 1);
 }` + "\x00"
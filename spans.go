@@ -0,0 +1,80 @@
+package glfont
+
+// Span is a piece of text drawn in its own color by PrintfSpans.
+type Span struct {
+	Text       string
+	R, G, B, A float32
+	Script     Script // Draws the text smaller, raised or lowered, Normal by default.
+}
+
+// Script represents the placement of a span of text relative to the baseline.
+type Script uint8
+
+// Known scripts.
+const (
+	Normal      Script = iota // On the baseline, at the scale of the line.
+	Superscript               // Smaller and raised, e.g.: the 2 of x²
+	Subscript                 // Smaller and lowered, e.g.: the 2 of H₂O
+)
+
+// PrintfSpans draws the text of the spans one after the other like a single Printf,
+// each in its color, with a single draw call. Kerning, tabs and newlines carry across
+// spans. The color set with SetColor is restored afterwards. Superscript and subscript
+// spans are drawn as set with SetScriptMetrics, and the text after them continues from
+// the end of their smaller glyphs.
+func (f *Font) PrintfSpans(x, y float32, scale float32, spans []Span) error {
+	var text []rune
+	var owner []int      //span of each rune of text
+	var scripts []Script //script of each rune of text, nil if they are all Normal
+	for i, span := range spans {
+		for _, r := range span.Text {
+			text = append(text, r)
+			owner = append(owner, i)
+		}
+		if span.Script != Normal && scripts == nil {
+			scripts = make([]Script, 0, len(text))
+		}
+	}
+	if scripts != nil {
+		for _, i := range owner {
+			scripts = append(scripts, spans[i].Script)
+		}
+	}
+
+	if len(text) == 0 {
+		return nil
+	}
+
+	saved := f.color
+	coords := f.scratch(scale)
+	var runs []textRun
+
+	f.layoutScripts(scale, text, scripts, func(i int, ch *character, src *Font, gx, gy float32) {
+		span := spans[owner[i]]
+		f.color = color{r: span.R, g: span.G, b: span.B, a: span.A}
+		s, rise := f.scriptScale(span.Script, scale)
+		coords = f.appendGlyph(coords, src, ch, x+gx, y+gy, s)
+		//decorations stay on the baseline of the line
+		runs = f.extendRun(runs, src, ch, x+gx, y+gy+f.down(rise), s)
+	}, nil)
+	coords = f.appendDecorations(coords, runs, scale)
+
+	f.color = saved
+
+	return f.draw(coords)
+}
+
+// SetScriptMetrics sets how superscript and subscript spans are drawn: at scale times the
+// scale of the line, with their baseline raised by rise or lowered by drop times the
+// ascent of the font. The defaults are 0.6, 0.3 and 0.3.
+func (f *Font) SetScriptMetrics(scale, rise, drop float32) {
+	f.mu.Lock()
+	f.script = scriptMetrics{scale: scale, rise: rise, drop: drop}
+	f.mu.Unlock()
+}
+
+//scriptMetrics places superscript and subscript text relative to the line
+type scriptMetrics struct {
+	scale      float32 //multiplier of the line scale
+	rise, drop float32 //distance of the baseline from the line one, in ascents
+}
@@ -0,0 +1,59 @@
+package glfont
+
+import "github.com/go-gl/gl/all-core/gl"
+
+// glState holds the parts of the GL state changed by drawing text.
+type glState struct {
+	program       int32
+	vao           int32
+	arrayBuffer   int32
+	activeTexture int32
+	texture       int32
+	manageBlend   bool
+	blend         bool
+	srcRGB        int32
+	dstRGB        int32
+	srcAlpha      int32
+	dstAlpha      int32
+}
+
+// saveState queries the current GL state. The texture saved is the one bound to
+// TEXTURE0, which is the unit used for the glyph atlas. The blending state is only
+// saved with manageBlend, when drawing changes it.
+func saveState(manageBlend bool) glState {
+	s := glState{manageBlend: manageBlend}
+	gl.GetIntegerv(gl.CURRENT_PROGRAM, &s.program)
+	gl.GetIntegerv(gl.VERTEX_ARRAY_BINDING, &s.vao)
+	gl.GetIntegerv(gl.ARRAY_BUFFER_BINDING, &s.arrayBuffer)
+	gl.GetIntegerv(gl.ACTIVE_TEXTURE, &s.activeTexture)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.GetIntegerv(gl.TEXTURE_BINDING_2D, &s.texture)
+	if !manageBlend {
+		return s
+	}
+	s.blend = gl.IsEnabled(gl.BLEND)
+	gl.GetIntegerv(gl.BLEND_SRC_RGB, &s.srcRGB)
+	gl.GetIntegerv(gl.BLEND_DST_RGB, &s.dstRGB)
+	gl.GetIntegerv(gl.BLEND_SRC_ALPHA, &s.srcAlpha)
+	gl.GetIntegerv(gl.BLEND_DST_ALPHA, &s.dstAlpha)
+	return s
+}
+
+// restore puts back the GL state saved by saveState.
+func (s glState) restore() {
+	gl.UseProgram(uint32(s.program))
+	gl.BindVertexArray(uint32(s.vao))
+	gl.BindBuffer(gl.ARRAY_BUFFER, uint32(s.arrayBuffer))
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, uint32(s.texture))
+	gl.ActiveTexture(uint32(s.activeTexture))
+	if !s.manageBlend {
+		return
+	}
+	if s.blend {
+		gl.Enable(gl.BLEND)
+	} else {
+		gl.Disable(gl.BLEND)
+	}
+	gl.BlendFuncSeparate(uint32(s.srcRGB), uint32(s.dstRGB), uint32(s.srcAlpha), uint32(s.dstAlpha))
+}
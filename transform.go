@@ -0,0 +1,203 @@
+package glfont
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// PrintfRotated draws a string to the screen like Printf, rotated by radians around x, y.
+// An angle of 0 is the same as Printf.
+func (f *Font) PrintfRotated(x, y float32, scale float32, radians float32, fs string, argv ...interface{}) error {
+
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	if len(indices) == 0 {
+		return nil
+	}
+
+	coords := f.scratch(scale)
+	start, backdrop := len(coords), len(f.backdrop)
+	coords = f.appendText(coords, x, y, scale, indices)
+	rotate(coords[start:], x, y, radians)
+	rotate(f.backdrop[backdrop:], x, y, radians)
+
+	return f.draw(coords)
+}
+
+// PrintfMatrix draws a string like Printf, with its vertices transformed by mvp into clip
+// space instead of being mapped from window pixels with the resolution. x, y and the glyph
+// sizes are in the units mvp is expecting, so this allows zooming, panning and world space text.
+// Text batched with Begin before the call is drawn first.
+func (f *Font) PrintfMatrix(mvp mgl32.Mat4, x, y float32, scale float32, fs string, argv ...interface{}) error {
+
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	if len(indices) == 0 {
+		return nil
+	}
+
+	f.flush()
+
+	f.transform = &mvp
+	defer func() { f.transform = nil }()
+
+	return f.render(f.appendText(f.scratch(scale), x, y, scale, indices))
+}
+
+// Printf3D draws a string like PrintfMatrix, in the scene drawn with the view and proj
+// matrices, with the origin of its first baseline at worldPos. scale is the size in world
+// units of a pixel of the font, and the text lies in the x, y plane of the world, reading
+// along x with y up. With billboard, the text faces the camera whatever its rotation.
+// The text is drawn over the scene unless SetDepthTest enables depth testing.
+func (f *Font) Printf3D(worldPos mgl32.Vec3, view, proj mgl32.Mat4, scale float32, billboard bool, fs string, argv ...interface{}) error {
+
+	//the layout is in font pixels, mapped to world units along the axes of the text
+	f.mu.RLock()
+	flip := f.yAxis == YDown
+	depthTest := f.depthTest
+	f.mu.RUnlock()
+	model := mgl32.Translate3D(worldPos.X(), worldPos.Y(), worldPos.Z())
+	if billboard {
+		//the inverse of the rotation of the view, its transpose
+		model = model.Mul4(view.Mat3().Transpose().Mat4())
+	}
+	if flip {
+		model = model.Mul4(mgl32.Scale3D(scale, -scale, scale))
+	} else {
+		model = model.Mul4(mgl32.Scale3D(scale, scale, scale))
+	}
+
+	if depthTest {
+		//test against the scene without writing depth, for the glyphs not to hide each other
+		var mask bool
+		enabled := gl.IsEnabled(gl.DEPTH_TEST)
+		gl.GetBooleanv(gl.DEPTH_WRITEMASK, &mask)
+		f.flush()
+		gl.Enable(gl.DEPTH_TEST)
+		gl.DepthMask(false)
+		defer func() {
+			gl.DepthMask(mask)
+			if !enabled {
+				gl.Disable(gl.DEPTH_TEST)
+			}
+		}()
+	}
+
+	return f.PrintfMatrix(proj.Mul4(view).Mul4(model), 0, 0, 1, fs, argv...)
+}
+
+// SetDepthTest chooses whether the text of Printf3D is hidden behind the scene, testing it
+// against the depth buffer without writing to it. It is disabled by default, drawing the
+// text over the scene like a label.
+func (f *Font) SetDepthTest(enabled bool) {
+	f.mu.Lock()
+	f.depthTest = enabled
+	f.mu.Unlock()
+}
+
+// PrintfFunc draws a string like Printf, calling fn for each glyph with the index of its rune
+// in the string, the rune and the position of its origin on the baseline. The glyph is drawn
+// moved by the offset fn returns, with its alpha multiplied by the alpha fn returns, or not
+// at all for an alpha of 0, e.g. to reveal text a glyph at a time or animate its glyphs.
+// The shadow and outline of a glyph fade with it.
+// Decorations and backgrounds stay where they would be without the offsets.
+func (f *Font) PrintfFunc(x, y float32, scale float32, fs string, fn func(index int, ch rune, pos mgl32.Vec2) (offset mgl32.Vec2, alpha float32)) error {
+
+	indices := []rune(fs)
+
+	if len(indices) == 0 {
+		return nil
+	}
+
+	coords := f.scratch(scale)
+	var runs []textRun
+
+	f.layout(scale, indices, func(i int, ch *character, src *Font, gx, gy float32) {
+		offset, alpha := fn(i, indices[i], mgl32.Vec2{x + gx, y + gy})
+		if alpha <= 0 {
+			return
+		}
+
+		start := len(coords)
+		coords = f.appendGlyph(coords, src, ch, x+gx+offset.X(), y+gy+offset.Y(), scale)
+		for v := start; v < len(coords); v++ {
+			coords[v][7] *= alpha
+		}
+		runs = f.extendRun(runs, src, ch, x+gx, y+gy, scale)
+	})
+
+	return f.draw(f.appendDecorations(coords, runs, scale))
+}
+
+// YAxis is the direction in which y coordinates grow on the screen.
+type YAxis uint8
+
+// Known y-axis conventions.
+const (
+	YDown YAxis = iota // y grows downwards from the top of the window, the default.
+	YUp                // y grows upwards from the bottom of the window.
+)
+
+// Ortho returns an orthographic projection mapping window pixels to clip space, with the
+// origin at the top left corner of the window for YDown and at the bottom left for YUp.
+// Ortho(width, height, YDown) maps pixels like the resolution given to LoadFont.
+func Ortho(width, height float32, yAxis YAxis) mgl32.Mat4 {
+	if yAxis == YUp {
+		return mgl32.Ortho2D(0, width, 0, height)
+	}
+	return mgl32.Ortho2D(0, width, height, 0)
+}
+
+// SetProjection draws all text with its vertices transformed by projection into clip space,
+// instead of mapping them from window pixels with the resolution, such as a matrix built
+// with Ortho or the camera of an engine. PrintfMatrix still overrides it for a single call.
+func (f *Font) SetProjection(projection mgl32.Mat4) {
+	//text batched so far keeps the previous projection
+	f.flush()
+
+	f.projection = &projection
+}
+
+// ClearProjection maps text from window pixels with the resolution again.
+func (f *Font) ClearProjection() {
+	f.flush()
+
+	f.projection = nil
+}
+
+// YAxis returns the direction in which y grows in the coordinates text is laid out in:
+// glyphs are placed above their baseline at smaller y for YDown.
+func (f *Font) YAxis() YAxis {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.yAxis
+}
+
+// SetYAxis chooses the direction in which y grows in the coordinates text is drawn at.
+// With YUp, such as with Ortho(width, height, YUp), glyphs extend to larger y above their
+// baseline and the following lines are at smaller y. The default is YDown, matching the
+// resolution mapping of LoadFont.
+func (f *Font) SetYAxis(yAxis YAxis) {
+	f.mu.Lock()
+	f.yAxis = yAxis
+	f.mu.Unlock()
+}
+
+// rotate rotates the vertices of coords by radians around x, y.
+func rotate(coords []point, x, y float32, radians float32) {
+	if radians == 0 {
+		return
+	}
+
+	sin, cos := math.Sincos(float64(radians))
+	s, c := float32(sin), float32(cos)
+	for i := range coords {
+		dx := coords[i][0] - x
+		dy := coords[i][1] - y
+		coords[i][0] = x + dx*c - dy*s
+		coords[i][1] = y + dx*s + dy*c
+	}
+}
@@ -6,10 +6,9 @@ import (
 	"image/draw"
 	"io"
 	"io/ioutil"
+	"sort"
 
 	"github.com/go-gl/gl/all-core/gl"
-	"github.com/golang/freetype"
-	"github.com/golang/freetype/truetype"
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 )
@@ -19,10 +18,192 @@ type character struct {
 	width    int //glyph width
 	height   int //glyph height
 	advance  int //glyph advance
+	vadvance int //glyph vertical advance
 	bearingH int //glyph bearing horizontal
 	bearingV int //glyph bearing vertical
 }
 
+//empty reports whether the glyph has no ink, and no quad drawn or room in the atlas
+func (char *character) empty() bool {
+	return char.width == 0 || char.height == 0
+}
+
+//lookup returns the character loaded for rune r, if it was packed when the font was loaded
+//or can be added to a dynamic atlas
+func (f *Font) lookup(r rune) (*character, bool) {
+	if char, ok := f.fontChar[r]; ok {
+		return char, true
+	}
+	if f.dynamic != nil {
+		f.faceMu.Lock()
+		defer f.faceMu.Unlock()
+		return f.dynamic.lookup(r)
+	}
+	return nil, false
+}
+
+//rasterizer measures and draws the glyphs of a font at a given scale
+type rasterizer struct {
+	glyphs  outlines
+	face    font.Face
+	scale   int32
+	dpi     float64
+	hinting font.Hinting
+	spread  int //empty pixels around glyphs, for their distance field
+}
+
+//measure returns the character of rune ch without its atlas position, and the bounds to draw it with
+func (ra *rasterizer) measure(ch rune) (*character, fixed.Rectangle26_6, error) {
+	gBnd, gAdv, ok := ra.face.GlyphBounds(ch)
+	if ok != true {
+		return nil, gBnd, fmt.Errorf("ttf face glyphBounds error")
+	}
+
+	char := ra.character(gBnd, gAdv)
+	char.vadvance = int(ra.glyphs.vadvance(fixed.Int26_6(float64(ra.scale)*ra.dpi/72*64), ch))
+	return char, gBnd, nil
+}
+
+//character returns the character of a glyph with bounds gBnd and advance gAdv, without its atlas position
+func (ra *rasterizer) character(gBnd fixed.Rectangle26_6, gAdv fixed.Int26_6) *character {
+	char := new(character)
+
+	gh := int32((gBnd.Max.Y - gBnd.Min.Y) >> 6)
+	gw := int32((gBnd.Max.X - gBnd.Min.X) >> 6)
+	char.advance = int(gAdv)
+
+	//glyphs without ink, like spaces, only advance and take no room in the atlas
+	if gw == 0 || gh == 0 {
+		return char
+	}
+
+	//The glyph's descent equals +bounds.Max.Y.
+	gdescent := int(gBnd.Max.Y) >> 6
+
+	//set w,h and adv, bearing V and bearing H in char
+	char.width = int(gw) + 2*ra.spread
+	char.height = int(gh) + 2*ra.spread
+	char.bearingV = gdescent + ra.spread
+	char.bearingH = (int(gBnd.Min.X) >> 6) - ra.spread
+
+	return char
+}
+
+//glyphMask returns the mask of a glyph drawn with its origin at dot, like font.Face.Glyph
+type glyphMask func(dot fixed.Point26_6) (dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool)
+
+//draw draws rune ch at the atlas position of char, turning it into a distance field if the rasterizer has a spread
+func (ra *rasterizer) draw(dst *image.Gray, ch rune, char *character, gBnd fixed.Rectangle26_6) error {
+	return ra.drawMask(dst, char, gBnd, func(dot fixed.Point26_6) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+		return ra.face.Glyph(dot, ch)
+	})
+}
+
+//drawMask is like draw for the glyph with bounds gBnd whose mask is returned by glyph
+func (ra *rasterizer) drawMask(dst *image.Gray, char *character, gBnd fixed.Rectangle26_6, glyph glyphMask) error {
+	//The glyph's ascent equals -bounds.Min.Y.
+	gAscent := int(-gBnd.Min.Y) >> 6
+
+	clip := image.Rect(char.x, char.y, char.x+char.width, char.y+char.height)
+
+	//set the glyph dot
+	px := 0 - (int(gBnd.Min.X) >> 6) + char.x + ra.spread
+	py := (gAscent) + char.y + ra.spread
+	dot := fixed.P(px, py)
+
+	// Draw the glyph from its mask to the atlas
+	dr, mask, maskp, _, ok := glyph(dot)
+	if !ok {
+		return fmt.Errorf("ttf face glyph error")
+	}
+	r := dr.Intersect(clip)
+	draw.DrawMask(dst, r, image.White, image.ZP, mask, maskp.Add(r.Min.Sub(dr.Min)), draw.Over)
+
+	if ra.spread > 0 {
+		distanceField(dst, clip, ra.spread)
+	}
+	return nil
+}
+
+//coverageRGBA returns the rect of the gray atlas as white texels with the coverage in alpha
+func coverageRGBA(gray *image.Gray, rect image.Rectangle) *image.RGBA {
+	rgba := image.NewRGBA(rect)
+	draw.Draw(rgba, rect, image.White, image.ZP, draw.Src)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			rgba.Pix[rgba.PixOffset(x, y)+3] = gray.GrayAt(x, y).Y
+		}
+	}
+	return rgba
+}
+
+//newTofu returns the character of a hollow box sitting on the baseline, sized
+//after the font ascent and surrounded by spread empty pixels
+func newTofu(ascent float32, spread int) *character {
+	h := int(ascent * 0.7)
+	if h < 3 {
+		h = 3
+	}
+	w := h * 2 / 3
+	if w < 3 {
+		w = 3
+	}
+	return &character{
+		width:    w + 2*spread,
+		height:   h + 2*spread,
+		advance:  (w + 2) << 6,
+		vadvance: (h + 2) << 6,
+		bearingH: 1 - spread,
+		bearingV: spread,
+	}
+}
+
+//drawTofu draws the outline of the tofu box in the atlas
+func drawTofu(img *image.Gray, tofu *character, spread int) {
+	box := image.Rect(tofu.x+spread, tofu.y+spread, tofu.x+tofu.width-spread, tofu.y+tofu.height-spread)
+	stroke := box.Dy()/12 + 1
+	inner := box.Inset(stroke)
+	for y := box.Min.Y; y < box.Max.Y; y++ {
+		for x := box.Min.X; x < box.Max.X; x++ {
+			if !(image.Point{x, y}).In(inner) {
+				img.Pix[img.PixOffset(x, y)] = 0xff
+			}
+		}
+	}
+}
+
+//packGlyphs assigns an atlas position to each char with a skyline packer, and reports
+//whether they all fit in an atlas of the given size
+func packGlyphs(chars []*character, width, height, margin int) bool {
+	//placing the tallest glyphs first leaves little room wasted beneath the shorter ones,
+	//ties keep their order so that the atlas is the same on every load
+	order := make([]*character, len(chars))
+	copy(order, chars)
+	sort.SliceStable(order, func(i, j int) bool { return order[i].height > order[j].height })
+
+	packer := newSkyline(width, height, margin)
+	for _, char := range order {
+		if !packer.place(char) {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(a float32) float32 {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func min(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
 func max(a, b float32) float32 {
 	if a > b {
 		return a
@@ -30,154 +211,507 @@ func max(a, b float32) float32 {
 	return b
 }
 
-//LoadTrueTypeFont builds a set of textures based on a ttf files gylphs
+func clamp01(a float32) float32 {
+	return max(0, min(a, 1))
+}
+
+// AtlasOptions controls how glyphs are packed into and sampled from the atlas.
+type AtlasOptions struct {
+	// Margin is the number of empty pixels between the glyphs and along the
+	// edges of the atlas. Larger margins keep mipmap levels from bleeding
+	// neighbours in. Like the other fields, 0 selects the default of 2, so a
+	// margin of exactly 0, for pixel fonts drawn with Nearest filtering at their
+	// loaded size, is set with NoMargin. Other negative margins are invalid.
+	Margin int
+	// Padding is the number of extra empty pixels kept around each glyph, on top
+	// of the margin, so that mipmap levels do not bleed neighbours in.
+	Padding int
+	// DisableMipmaps samples the atlas with plain linear filtering, which keeps
+	// glyph edges crisp when rendering at the atlas's native size.
+	DisableMipmaps bool
+	// RGBA stores the atlas as a 4 channel texture instead of a single GL_RED
+	// channel, for drivers without support for GL_RED textures. Its texels are
+	// white with the coverage in alpha, so that filtered edges blend towards the
+	// text color rather than black.
+	RGBA bool
+	// SDF stores a signed distance field of each glyph instead of its coverage,
+	// which keeps edges sharp when text is scaled far above its loaded size.
+	SDF bool
+	// Spread is the distance in pixels covered by the distance field on each
+	// side of the glyph edges when SDF is set. It defaults to 4.
+	Spread int
+	// Dynamic keeps the font around to rasterize runes outside of the loaded
+	// range the first time they are drawn or measured, and packs them into the
+	// free space of an atlas of DynamicSize by DynamicSize pixels. Runes that
+	// no longer fit are drawn as missing. This keeps the atlas small for text
+	// using few runes from a large range.
+	Dynamic bool
+	// DynamicSize is the size of the dynamic atlas. It defaults to 1024.
+	DynamicSize int
+	// GammaCorrect raises the glyph coverage to 1/2.2 so that the edges of
+	// light text on a dark background blend as if in linear light rather than
+	// in the sRGB values of the framebuffer, which makes it look thinner than
+	// intended. Dark text on a light background would look bolder with it.
+	GammaCorrect bool
+	// Filter is how the atlas is sampled, Linear by default. Nearest also
+	// disables mipmaps.
+	Filter FilterMode
+}
+
+// FilterMode represents how the atlas texture is sampled when text is drawn.
+type FilterMode uint8
+
+// Known filter modes.
+const (
+	Linear  FilterMode = iota // Smooth, with mipmaps unless disabled.
+	Nearest                   // Blocky, for pixel art and bitmap fonts drawn at whole multiples of their size.
+)
+
+// NoMargin is the AtlasOptions.Margin packing the glyphs of the atlas with no empty pixels
+// between them, since a Margin of 0 selects the default.
+const NoMargin = -1
+
+//margin returns the number of empty pixels kept between the glyphs of the atlas
+func (atlas AtlasOptions) margin() int {
+	margin := 2
+	if atlas.Margin > 0 {
+		margin = atlas.Margin
+	} else if atlas.Margin == NoMargin {
+		margin = 0
+	}
+	return margin + atlas.Padding
+}
+
+//mipmaps reports whether the atlas sampled with atlas has mipmaps
+func (atlas AtlasOptions) mipmaps() bool {
+	return !atlas.DisableMipmaps && atlas.Filter != Nearest
+}
+
+//srgbGamma is the gamma coverage is corrected with by AtlasOptions.GammaCorrect
+const srgbGamma = 2.2
+
+//LoadTrueTypeFont builds a set of textures based on a ttf files gylphs.
+//All glyphs from low to high are packed into a single atlas of at least 1024x1024,
+//grown to the next power of two as needed. An error is returned if the range does
+//not fit in the largest texture supported by the driver.
+//With dir RightToLeft, text is laid out leftward from the x passed to Printf, in the
+//order of its runes. With TopToBottom, lines are columns centered on x that hang
+//down from y, each newline starting a column to the left of the previous one.
 func LoadTrueTypeFont(program uint32, r io.Reader, scale int32, low, high rune, dir Direction) (*Font, error) {
+	return LoadTrueTypeFontAtlas(program, r, scale, low, high, dir, AtlasOptions{})
+}
+
+// LoadTrueTypeFontAtlas is like LoadTrueTypeFont with control over the packing and sampling of the atlas.
+func LoadTrueTypeFontAtlas(program uint32, r io.Reader, scale int32, low, high rune, dir Direction, atlas AtlasOptions) (*Font, error) {
+	return LoadTrueTypeFontWithOptions(program, r, Options{Scale: scale, Low: low, High: high, Direction: dir, Atlas: atlas})
+}
+
+// Hinting selects how glyph outlines are fitted to the pixel grid when they are rasterized.
+type Hinting uint8
+
+// Known hinting levels.
+const (
+	HintingFull     Hinting = iota // Sharpest at small sizes.
+	HintingVertical                // Only vertically, drawn like HintingFull by freetype for now.
+	HintingNone                    // Keeps the outlines, lighter at large sizes.
+)
+
+//font returns the freetype hinting of h
+func (h Hinting) font() font.Hinting {
+	switch h {
+	case HintingVertical:
+		return font.HintingVertical
+	case HintingNone:
+		return font.HintingNone
+	}
+	return font.HintingFull
+}
+
+// Options controls how a font is loaded by LoadTrueTypeFontWithOptions. The zero value
+// of each field but Scale selects its default.
+type Options struct {
+	// Scale is the size of the font in pixels. It is required.
+	Scale int32
+	// Low and High are the first and last runes packed into the atlas. They
+	// default to 32 and 256 when both are 0 and Ranges is empty.
+	Low, High rune
+	// Ranges are packed into the atlas on top of the range from Low to High,
+	// so that sparse sets such as ASCII and the box drawing block load without
+	// the runes between them. With Low and High left at 0, the atlas only has
+	// the runes of Ranges and Runes.
+	Ranges []RuneRange
+	// Runes are packed into the atlas on top of the range from Low to High,
+	// for sparse sets such as a few emoji or CJK extension runes far from it.
+	Runes []rune
+	// Direction in which the text is laid out, LeftToRight by default.
+	Direction Direction
+	// DPI is the density the glyphs are rasterized at, 72 by default. Text is
+	// still laid out and drawn in logical pixels, so a DPI of 144 draws text of
+	// the same size with twice as many pixels, e.g. on a display with a device
+	// pixel ratio of 2. Font.SetContentScale changes it after loading.
+	DPI float64
+	// Hinting is how glyph outlines are fitted to the pixel grid, HintingFull
+	// by default.
+	Hinting Hinting
+	// Index is the face to load from a TrueType collection, 0 for the first one
+	// or for a single font.
+	Index int
+	// AtlasWidth and AtlasHeight are the initial size of the atlas, grown as
+	// needed unless it is dynamic. They default to 1024.
+	AtlasWidth, AtlasHeight int
+	// Atlas controls the packing and sampling of the atlas.
+	Atlas AtlasOptions
+}
+
+// RuneRange is a range of runes loaded into the atlas, from Low to High included.
+type RuneRange struct {
+	Low, High rune
+}
+
+// LoadTrueTypeFontWithOptions builds a set of textures based on a ttf files gylphs like
+// LoadTrueTypeFont, with the settings of opts.
+func LoadTrueTypeFontWithOptions(program uint32, r io.Reader, opts Options) (*Font, error) {
 	data, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
 
-	// Read the truetype font.
-	ttf, err := truetype.Parse(data)
+	p, err := parseFont(data, opts.Index)
 	if err != nil {
 		return nil, err
 	}
 
-	//make Font stuct type
-	f := new(Font)
-	f.fontChar = make([]*character, 0, high-low+1)
-	f.program = program            //set shader program
-	f.SetColor(1.0, 1.0, 1.0, 1.0) //set default white
+	return p.load(program, opts)
+}
 
-	//create new face
-	ttfFace := truetype.NewFace(ttf, &truetype.Options{
-		Size:    float64(scale),
-		DPI:     72,
-		Hinting: font.HintingFull,
-	})
+//parsedFont is a font file parsed once, to be loaded at several sizes
+type parsedFont struct {
+	data   []byte // Contents of the font file, a collection or a single font.
+	face   []byte // Standalone font of the face loaded from data.
+	glyphs outlines
+}
 
-	var lineHeight float32
-	f.atlasWidth = 1024
-	f.atlasHeight = 1024
-	for ch := low; ch <= high; ch++ {
-		gBnd, _, ok := ttfFace.GlyphBounds(ch)
-		if ok != true {
-			return nil, fmt.Errorf("ttf face glyphBounds error")
-		}
-		gh := int32((gBnd.Max.Y - gBnd.Min.Y) >> 6)
-		lineHeight = max(lineHeight, float32(gh))
+//parseFont parses the face at index of the font file in data
+func parseFont(data []byte, index int) (parsedFont, error) {
+	//a single face of a collection
+	face, err := collectionFace(data, index)
+	if err != nil {
+		return parsedFont{}, err
 	}
 
-	//create image to draw glyph
-	fg, bg := image.White, image.Black
-	rect := image.Rect(0, 0, int(f.atlasWidth), int(f.atlasHeight))
-	rgba := image.NewRGBA(rect)
-	draw.Draw(rgba, rgba.Bounds(), bg, image.ZP, draw.Src)
-
-	margin := 2
-	x := margin
-	y := margin
+	// Read the truetype or OpenType font.
+	glyphs, err := parseOutlines(face)
+	if err != nil {
+		return parsedFont{}, err
+	}
 
-	//make each gylph
-	for ch := low; ch <= high; ch++ {
-		char := new(character)
+	return parsedFont{data: data, face: face, glyphs: glyphs}, nil
+}
 
-		gBnd, gAdv, ok := ttfFace.GlyphBounds(ch)
-		if ok != true {
-			return nil, fmt.Errorf("ttf face glyphBounds error")
-		}
+//load builds the font with the settings of opts, and its GL objects drawing it with program
+func (p parsedFont) load(program uint32, opts Options) (*Font, error) {
+	//the atlas grows up to the largest texture supported by the driver
+	var maxSize int32
+	gl.GetIntegerv(gl.MAX_TEXTURE_SIZE, &maxSize)
 
-		gh := int32((gBnd.Max.Y - gBnd.Min.Y) >> 6)
-		gw := int32((gBnd.Max.X - gBnd.Min.X) >> 6)
+	f, gray, err := p.build(opts, int(maxSize))
+	if err != nil {
+		return nil, err
+	}
+	f.program = program                    //set shader program
+	f.locations = lookupLocations(program) //resolve shader inputs
+	f.createGLObjects(gray)
 
-		//if gylph has no dimensions set to a max value
-		if gw == 0 || gh == 0 {
-			gBnd = ttf.Bounds(fixed.Int26_6(scale))
-			gw = int32((gBnd.Max.X - gBnd.Min.X) >> 6)
-			gh = int32((gBnd.Max.Y - gBnd.Min.Y) >> 6)
+	return f, nil
+}
 
-			//above can sometimes yield 0 for font smaller than 48pt, 1 is minimum
-			if gw == 0 || gh == 0 {
-				gw = 1
-				gh = 1
-			}
-		}
+//createGLObjects uploads the atlas in gray to a new texture and creates the buffers the quads are drawn from
+func (f *Font) createGLObjects(gray *image.Gray) {
+	// Generate texture
+	gl.GenTextures(1, &f.textureID)
+	f.uploadAtlas(gray)
 
-		//The glyph's ascent and descent equal -bounds.Min.Y and +bounds.Max.Y.
-		gAscent := int(-gBnd.Min.Y) >> 6
-		gdescent := int(gBnd.Max.Y) >> 6
-
-		//set w,h and adv, bearing V and bearing H in char
-		char.x = x
-		char.y = y
-		char.width = int(gw)
-		char.height = int(gh)
-		char.advance = int(gAdv)
-		char.bearingV = gdescent
-		char.bearingH = (int(gBnd.Min.X) >> 6)
-
-		clip := image.Rect(x, y, x+int(gw), y+int(gh))
-
-		//create a freetype context for drawing
-		c := freetype.NewContext()
-		c.SetDPI(72)
-		c.SetFont(ttf)
-		c.SetFontSize(float64(scale))
-		c.SetClip(clip)
-		c.SetDst(rgba)
-		c.SetSrc(fg)
-		c.SetHinting(font.HintingFull)
-
-		//set the glyph dot
-		px := 0 - (int(gBnd.Min.X) >> 6) + x
-		py := (gAscent) + y
-		pt := freetype.Pt(px, py)
-
-		x += int(gw) + margin
-		if x+int(gw)+margin > int(f.atlasWidth) {
-			x = 0
-			y += int(lineHeight) + margin
-		}
-
-		// Draw the text from mask to image
-		_, err = c.DrawString(string(ch), pt)
-		if err != nil {
-			return nil, err
-		}
+	//preallocate room for 256 glyphs of 6 vertices, it grows as needed when drawing
+	f.vboSize = 256 * 6 * pointSize
+	f.vao, f.vbo = f.newVertexArray(f.vboSize, nil, gl.DYNAMIC_DRAW)
+}
 
-		//add char to fontChar list
-		f.fontChar = append(f.fontChar, char)
-	}
+//uploadAtlas replaces the contents of the atlas texture of the font with gray
+func (f *Font) uploadAtlas(gray *image.Gray) {
+	atlas := f.options.Atlas
+	rect := gray.Rect
 
-	// Generate texture
-	gl.GenTextures(1, &f.textureID)
 	gl.BindTexture(gl.TEXTURE_2D, f.textureID)
 	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-
-	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, int32(rgba.Rect.Dx()), int32(rgba.Rect.Dy()), 0,
-		gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgba.Pix))
+	switch {
+	case atlas.Filter == Nearest:
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	case atlas.DisableMipmaps:
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	default:
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	}
+	//glyphs at the edges of the atlas are not blended with the opposite edge when filtered
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+
+	if atlas.RGBA {
+		rgba := coverageRGBA(gray, rect)
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, int32(rgba.Rect.Dx()), int32(rgba.Rect.Dy()), 0,
+			gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgba.Pix))
+	} else {
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.R8, int32(gray.Rect.Dx()), int32(gray.Rect.Dy()), 0,
+			gl.RED, gl.UNSIGNED_BYTE, gl.Ptr(gray.Pix))
+	}
 
-	gl.GenerateMipmap(gl.TEXTURE_2D)
+	if atlas.mipmaps() {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
 	gl.BindTexture(gl.TEXTURE_2D, 0)
+}
 
+//newVertexArray returns a VAO reading the points of a new VBO of size bytes, filled with data
+//if it is not nil, laid out for the attributes of the font shader
+func (f *Font) newVertexArray(size int, data []point, usage uint32) (vao, vbo uint32) {
 	// Configure VAO/VBO for texture quads
-	gl.GenVertexArrays(1, &f.vao)
-	gl.GenBuffers(1, &f.vbo)
-	gl.BindVertexArray(f.vao)
-	gl.BindBuffer(gl.ARRAY_BUFFER, f.vbo)
+	gl.GenVertexArrays(1, &vao)
+	gl.GenBuffers(1, &vbo)
+	gl.BindVertexArray(vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
+
+	if data != nil {
+		gl.BufferData(gl.ARRAY_BUFFER, size, gl.Ptr(data), usage)
+	} else {
+		gl.BufferData(gl.ARRAY_BUFFER, size, nil, usage)
+	}
 
-	vertAttrib := uint32(gl.GetAttribLocation(f.program, gl.Str("vert\x00")))
-	gl.EnableVertexAttribArray(vertAttrib)
-	gl.VertexAttribPointer(vertAttrib, 2, gl.FLOAT, false, 4*4, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(f.vertAttrib)
+	gl.VertexAttribPointer(f.vertAttrib, 2, gl.FLOAT, false, pointSize, gl.PtrOffset(0))
 
-	texCoordAttrib := uint32(gl.GetAttribLocation(f.program, gl.Str("vertTexCoord\x00")))
-	gl.EnableVertexAttribArray(texCoordAttrib)
-	gl.VertexAttribPointer(texCoordAttrib, 2, gl.FLOAT, false, 4*4, gl.PtrOffset(2*4))
+	gl.EnableVertexAttribArray(f.texCoordAttrib)
+	gl.VertexAttribPointer(f.texCoordAttrib, 2, gl.FLOAT, false, pointSize, gl.PtrOffset(2*4))
+
+	gl.EnableVertexAttribArray(f.colorAttrib)
+	gl.VertexAttribPointer(f.colorAttrib, 4, gl.FLOAT, false, pointSize, gl.PtrOffset(4*4))
 
 	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
 	gl.BindVertexArray(0)
 
-	return f, nil
+	return vao, vbo
+}
+
+//buildFont rasterizes the glyphs of the font in data into an atlas no larger than maxSize
+//pixels on each side, and returns the font without any of its GL objects and the atlas
+func buildFont(data []byte, opts Options, maxSize int) (*Font, *image.Gray, error) {
+	p, err := parseFont(data, opts.Index)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p.build(opts, maxSize)
+}
+
+//newFont returns a font loaded with opts, without any glyph, with the default settings
+func newFont(opts Options) *Font {
+	//make Font stuct type
+	f := new(Font)
+	f.fontChar = make(map[rune]*character)
+	f.dir = opts.Direction
+	f.density = float32(opts.DPI / 72)
+	f.gamma = 1
+	if opts.Atlas.GammaCorrect {
+		f.gamma = srgbGamma
+	}
+	f.options = opts
+
+	f.SetColor(1.0, 1.0, 1.0, 1.0) //set default white
+	f.lineSpacing = 1              //natural line height
+	f.tabWidth = 4                 //tab stops every 4 spaces
+	f.restoreState = true          //leave the GL state as found
+	f.manageBlend = true           //enable blending while drawing
+	f.showMissing = true           //draw a box for missing runes
+	f.opacity = 1                  //opaque
+	f.script = scriptMetrics{scale: 0.6, rise: 0.3, drop: 0.3}
+	return f
+}
+
+//build is like buildFont with the font already parsed
+func (p parsedFont) build(opts Options, maxSize int) (*Font, *image.Gray, error) {
+	if opts.Scale <= 0 {
+		return nil, nil, fmt.Errorf("font scale %d is not positive", opts.Scale)
+	}
+	if opts.Atlas.Margin < 0 && opts.Atlas.Margin != NoMargin {
+		return nil, nil, fmt.Errorf("atlas margin %d is negative, use NoMargin for none", opts.Atlas.Margin)
+	}
+	if opts.Low == 0 && opts.High == 0 && len(opts.Ranges) == 0 {
+		opts.Low, opts.High = 32, 256
+	}
+	if opts.DPI <= 0 {
+		opts.DPI = 72
+	}
+	ranges := opts.Ranges
+	if opts.Low != 0 || opts.High != 0 {
+		ranges = append([]RuneRange{{opts.Low, opts.High}}, ranges...)
+	}
+	for _, rr := range ranges {
+		if rr.High < rr.Low {
+			return nil, nil, fmt.Errorf("glyph range %d-%d is empty", rr.Low, rr.High)
+		}
+	}
+	scale, atlas := opts.Scale, opts.Atlas
+	data, glyphs := p.face, p.glyphs
+
+	f := newFont(opts)
+	f.source = p.data
+
+	//create new face
+	face := glyphs.newFace(float64(scale), opts.DPI, opts.Hinting.font())
+	f.face = face
+
+	//vertical metrics as defined by the font, line height is used to advance on newlines
+	metrics := face.Metrics()
+	f.ascent = float32(metrics.Ascent) / 64 / f.density
+	f.descent = float32(metrics.Descent) / 64 / f.density
+	f.lineHeight = f.ascent + f.descent
+	f.loadDecorations(data, scale)
+
+	//distance fields extend past the glyph edges
+	spread := 0
+	if atlas.SDF {
+		spread = atlas.Spread
+		if spread <= 0 {
+			spread = 4
+		}
+		f.sdf = true
+		f.spread = spread
+	}
+
+	raster := &rasterizer{glyphs: glyphs, face: face, scale: scale, dpi: opts.DPI, hinting: opts.Hinting.font(), spread: spread}
+
+	//the runes of the ranges and the extra ones, in the order they are packed
+	var runes []rune
+	for _, rr := range ranges {
+		for r := rr.Low; r <= rr.High; r++ {
+			runes = append(runes, r)
+		}
+	}
+	runes = append(runes, opts.Runes...)
+
+	//measure each gylph, skipping the runes the font has none for
+	var packed []*character
+	var bounds []fixed.Rectangle26_6
+	loaded := runes[:0]
+	for _, r := range runes {
+		if _, dup := f.fontChar[r]; dup || !glyphs.has(r) {
+			continue
+		}
+		char, gBnd, err := raster.measure(r)
+		if err != nil {
+			//a broken glyph leaves the rune missing instead of failing the font
+			f.skipped = append(f.skipped, r)
+			continue
+		}
+
+		//add char to fontChar map
+		f.fontChar[r] = char
+		if char.empty() {
+			continue
+		}
+		loaded = append(loaded, r)
+		packed = append(packed, char)
+		bounds = append(bounds, gBnd)
+	}
+
+	//ligatures of the loaded runes, drawn in the atlas with them
+	ligatures := raster.ligatures(data, f.fontChar)
+	for _, lig := range ligatures {
+		if lig.char.empty() {
+			continue
+		}
+		packed = append(packed, lig.char)
+	}
+
+	//hollow box drawn in place of missing runes
+	f.tofu = newTofu(f.ascent*f.density, spread)
+	f.solid = &character{width: 4, height: 4}
+	packed = append(packed, f.tofu, f.solid)
+
+	margin := atlas.margin()
+	atlasWidth, atlasHeight := 1024, 1024
+	if opts.AtlasWidth > 0 {
+		atlasWidth = opts.AtlasWidth
+	}
+	if opts.AtlasHeight > 0 {
+		atlasHeight = opts.AtlasHeight
+	}
+	if atlas.Dynamic {
+		//a fixed size atlas with room left for the glyphs added later
+		if atlas.DynamicSize > 0 {
+			atlasWidth, atlasHeight = atlas.DynamicSize, atlas.DynamicSize
+		}
+		packer := newSkyline(atlasWidth, atlasHeight, margin)
+		for _, char := range packed {
+			if !packer.place(char) {
+				return nil, nil, fmt.Errorf("%d glyphs do not fit in a %dx%d atlas", len(packed), atlasWidth, atlasHeight)
+			}
+		}
+		f.dynamic = &dynamicAtlas{
+			raster:  raster,
+			packer:  packer,
+			glyphs:  make(map[rune]*character),
+			bounds:  make(map[rune]fixed.Rectangle26_6),
+			rgba:    atlas.RGBA,
+			mipmaps: atlas.mipmaps(),
+		}
+	} else {
+		//grow the atlas to the next power of two until every glyph fits
+		for !packGlyphs(packed, atlasWidth, atlasHeight, margin) {
+			if atlasWidth <= atlasHeight {
+				atlasWidth *= 2
+			} else {
+				atlasHeight *= 2
+			}
+			if atlasWidth > maxSize || atlasHeight > maxSize {
+				return nil, nil, fmt.Errorf("%d glyphs do not fit in a %dx%d atlas", len(packed), maxSize, maxSize)
+			}
+		}
+	}
+	f.atlasWidth = float32(atlasWidth)
+	f.atlasHeight = float32(atlasHeight)
+
+	//create image to draw glyph coverage, black is empty
+	rect := image.Rect(0, 0, int(f.atlasWidth), int(f.atlasHeight))
+	gray := image.NewGray(rect)
+	if f.dynamic != nil {
+		f.dynamic.img = gray
+	}
+
+	//draw each gylph
+	for i, r := range loaded {
+		if err := raster.draw(gray, r, f.fontChar[r], bounds[i]); err != nil {
+			delete(f.fontChar, r)
+			f.skipped = append(f.skipped, r)
+		}
+	}
+
+	for _, lig := range ligatures {
+		if !lig.char.empty() && lig.draw(raster, gray) != nil {
+			continue
+		}
+		if f.ligatures == nil {
+			f.ligatures = make(map[rune][]*ligature)
+		}
+		f.ligatures[lig.runes[0]] = append(f.ligatures[lig.runes[0]], lig)
+	}
+	sort.Slice(f.skipped, func(i, j int) bool { return f.skipped[i] < f.skipped[j] })
+
+	drawTofu(gray, f.tofu, spread)
+	if atlas.SDF {
+		distanceField(gray, image.Rect(f.tofu.x, f.tofu.y, f.tofu.x+f.tofu.width, f.tofu.y+f.tofu.height), spread)
+	}
+	draw.Draw(gray, image.Rect(f.solid.x, f.solid.y, f.solid.x+f.solid.width, f.solid.y+f.solid.height), image.White, image.ZP, draw.Src)
+
+	return f, gray, nil
 }
@@ -0,0 +1,163 @@
+package glfont
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/gl/all-core/gl"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+//atlasRects returns the atlas rectangles of the glyphs of f with ink, by the rune they are drawn for
+func atlasRects(f *Font) map[rune]image.Rectangle {
+	rects := make(map[rune]image.Rectangle)
+	add := func(r rune, char *character) {
+		if !char.empty() {
+			rects[r] = image.Rect(char.x, char.y, char.x+char.width, char.y+char.height)
+		}
+	}
+	for r, char := range f.fontChar {
+		add(r, char)
+	}
+	//the placeholders have no rune of their own
+	add(-1, f.tofu)
+	add(-2, f.solid)
+	return rects
+}
+
+func TestAtlasFitsLargeRange(t *testing.T) {
+	f := loadTestFont(t, Options{Scale: 24, Low: 0x20, High: 0x4ff})
+
+	rects := atlasRects(f)
+	if len(rects) < 500 {
+		t.Fatalf("only %d glyphs loaded from 0x20-0x4ff", len(rects))
+	}
+
+	atlas := image.Rect(0, 0, int(f.atlasWidth), int(f.atlasHeight))
+	for r, rect := range rects {
+		if !rect.In(atlas) {
+			t.Errorf("glyph %U at %v is outside of the %v atlas", r, rect, atlas.Max)
+		}
+	}
+	for a, ra := range rects {
+		for b, rb := range rects {
+			if a < b && ra.Overlaps(rb) {
+				t.Errorf("glyphs %U at %v and %U at %v overlap", a, ra, b, rb)
+			}
+		}
+	}
+}
+
+func TestAtlasGlyphsInsetByMargin(t *testing.T) {
+	for _, atlas := range []AtlasOptions{
+		{},
+		{Margin: 5},
+		{Margin: 1, Padding: 3},
+		{Margin: NoMargin},
+	} {
+		f := loadTestFont(t, Options{Scale: 20, Atlas: atlas})
+		margin := atlas.margin()
+		for r, rect := range atlasRects(f) {
+			if rect.Min.X < margin || rect.Min.Y < margin {
+				t.Errorf("margin %d: glyph %U at %v is closer than the margin to the atlas edge", margin, r, rect)
+			}
+		}
+	}
+}
+
+func TestNegativeMarginInvalid(t *testing.T) {
+	_, _, err := buildFont(goregular.TTF, Options{Scale: 20, Atlas: AtlasOptions{Margin: -2}}, 8192)
+	if err == nil {
+		t.Fatal("a margin of -2 loads, want an error")
+	}
+	if got := (AtlasOptions{Margin: NoMargin}).margin(); got != 0 {
+		t.Errorf("NoMargin keeps %d pixels between the glyphs, want 0", got)
+	}
+	if got := (AtlasOptions{}).margin(); got != 2 {
+		t.Errorf("a margin of 0 keeps %d pixels between the glyphs, want the default of 2", got)
+	}
+}
+
+func TestAtlasClampedToEdge(t *testing.T) {
+	//the test needs a current OpenGL context, which plain go test does not create
+	if err := gl.Init(); err != nil {
+		t.Skipf("no OpenGL: %v", err)
+	}
+	if gl.GetString(gl.VERSION) == nil {
+		t.Skip("no current OpenGL context")
+	}
+
+	f, gray, err := buildFont(goregular.TTF, Options{Scale: 20}, 8192)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.createGLObjects(gray)
+	defer f.Close()
+
+	gl.BindTexture(gl.TEXTURE_2D, f.textureID)
+	defer gl.BindTexture(gl.TEXTURE_2D, 0)
+	for _, param := range []uint32{gl.TEXTURE_WRAP_S, gl.TEXTURE_WRAP_T} {
+		var wrap int32
+		gl.GetTexParameteriv(gl.TEXTURE_2D, param, &wrap)
+		if wrap != gl.CLAMP_TO_EDGE {
+			t.Errorf("atlas wrap parameter %#x is %#x, want CLAMP_TO_EDGE", param, wrap)
+		}
+	}
+}
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+func TestGammaCorrectEdges(t *testing.T) {
+	plain, gray, err := buildFont(goregular.TTF, Options{Scale: 20}, 8192)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrected := loadTestFont(t, Options{Scale: 20, Atlas: AtlasOptions{GammaCorrect: true}})
+	if plain.gamma != 1 || corrected.gamma != srgbGamma {
+		t.Fatalf("gamma is %g without GammaCorrect and %g with it", plain.gamma, corrected.gamma)
+	}
+
+	//the after column is only what the shader draws as long as it corrects with this line
+	if !strings.Contains(fragmentFontShader, gammaCorrection) {
+		t.Fatalf("the fragment shader does not correct the coverage with %q", gammaCorrection)
+	}
+
+	//the coverage of a row across the stem of l, before and after the correction of the shader
+	var b strings.Builder
+	fmt.Fprintln(&b, "# Coverage across the stem of l in Go Regular at scale 20, drawn white on black.")
+	fmt.Fprintln(&b, "# before: the coverage of the atlas, drawn as is without GammaCorrect. The partly")
+	fmt.Fprintln(&b, "#         covered edge pixels blend too dark, making light text on a dark")
+	fmt.Fprintln(&b, "#         background look thin.")
+	fmt.Fprintln(&b, "# after:  the coverage the gamma line of the fragment shader gives with")
+	fmt.Fprintln(&b, "#         GammaCorrect, computed on the CPU by correctCoverage, raised to 1/2.2")
+	fmt.Fprintln(&b, "#         so the edges blend as in linear light and the stem keeps its weight.")
+	fmt.Fprintln(&b, "# x  before  after")
+	char := plain.fontChar['l']
+	y := char.y + char.height/2
+	for x := char.x; x < char.x+char.width; x++ {
+		before := float32(gray.GrayAt(x, y).Y) / 255
+		after := correctCoverage(before, corrected.gamma)
+		if after < before || (before > 0 && before < 1 && after <= before) {
+			t.Errorf("coverage %.3f is corrected to %.3f", before, after)
+		}
+		fmt.Fprintf(&b, "%3d  %.3f  %.3f\n", x-char.x, before, after)
+	}
+
+	golden := "testdata/gamma.golden"
+	if *update {
+		if err := ioutil.WriteFile(golden, []byte(b.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != string(want) {
+		t.Errorf("edge coverage differs from %s, run go test -update after checking it:\n%s", golden, b.String())
+	}
+}
@@ -0,0 +1,293 @@
+package glfont
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/all-core/gl"
+)
+
+// PrintfWrap draws a string to the screen like Printf, breaking it on spaces so that no line
+// is wider than maxWidth. Words wider than maxWidth on their own are broken between runes.
+func (f *Font) PrintfWrap(x, y float32, scale float32, maxWidth float32, fs string, argv ...interface{}) error {
+
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	if len(indices) == 0 {
+		return nil
+	}
+
+	coords := f.scratch(scale)
+	for _, line := range f.wrapLines(scale, maxWidth, indices) {
+		coords = f.appendText(coords, x, y, scale, line)
+		x, y = f.nextLine(x, y, scale)
+	}
+
+	return f.draw(coords)
+}
+
+// PrintfJustified draws a string to the screen wrapped like PrintfWrap, stretching the spaces
+// of each line so that it is exactly width wide. The last line of each paragraph, lines
+// without spaces and lines already wider than width are drawn as they are.
+func (f *Font) PrintfJustified(x, y float32, scale float32, width float32, fs string, argv ...interface{}) error {
+
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	if len(indices) == 0 {
+		return nil
+	}
+
+	coords := f.scratch(scale)
+	for _, paragraph := range splitLines(indices) {
+		lines := f.wrapLines(scale, width, paragraph)
+		for i, line := range lines {
+			if i < len(lines)-1 {
+				coords = f.appendJustified(coords, x, y, scale, width, line)
+			} else {
+				coords = f.appendText(coords, x, y, scale, line)
+			}
+			x, y = f.nextLine(x, y, scale)
+		}
+	}
+
+	return f.draw(coords)
+}
+
+// appendJustified appends the quads of a single line like appendText, with the extra
+// space needed for it to be width wide spread evenly between its spaces.
+func (f *Font) appendJustified(coords []point, x, y float32, scale float32, width float32, line []rune) []point {
+	spaces := 0
+	for _, r := range line {
+		if r == ' ' {
+			spaces++
+		}
+	}
+
+	extra := width - f.lineWidth(scale, line)
+	if extra <= 0 || spaces == 0 || f.dir == TopToBottom {
+		return f.appendText(coords, x, y, scale, line)
+	}
+	gap := f.forward(extra / float32(spaces))
+
+	//the runes are laid out in visual order, each moved by the spaces laid out before it
+	var runs []textRun
+	var shift float32
+	f.layout(scale, line, func(i int, ch *character, src *Font, gx, gy float32) {
+		gx += shift
+		coords = f.appendGlyph(coords, src, ch, x+gx, y+gy, scale)
+		runs = f.extendRun(runs, src, ch, x+gx, y+gy, scale)
+		if line[i] == ' ' {
+			shift += gap
+		}
+	})
+
+	return f.appendDecorations(coords, runs, scale)
+}
+
+// MeasureWrapped returns the width of the widest line and the number of lines of a string
+// wrapped to maxWidth the same way PrintfWrap does.
+func (f *Font) MeasureWrapped(scale float32, maxWidth float32, fs string, argv ...interface{}) (width float32, lines int) {
+
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	if len(indices) == 0 {
+		return 0, 0
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	wrapped := f.wrapLines(scale, maxWidth, indices)
+	for _, line := range wrapped {
+		width = max(width, f.lineWidth(scale, line))
+	}
+
+	return width, len(wrapped)
+}
+
+// WrapLineCount returns the number of lines PrintfWrap draws a string on when wrapped to
+// maxWidth, breaking it the same way: a trailing newline starts an empty last line, and
+// words wider than maxWidth take as many lines as they are broken into. The baselines of
+// the lines are LineHeight(scale) times the line spacing apart.
+func (f *Font) WrapLineCount(scale float32, maxWidth float32, fs string, argv ...interface{}) int {
+
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	if len(indices) == 0 {
+		return 0
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return len(f.wrapLines(scale, maxWidth, indices))
+}
+
+// ForEachWrappedLine wraps a string to maxWidth the same way PrintfWrap does and calls fn
+// for each line with its index, its text, the y of its baseline relative to the first one
+// and its width, e.g. to draw a background or a timestamp by each line of a chat log before
+// drawing it with Printf. fn may call the methods of the font. The lines of vertical text
+// are columns, all at a y of 0.
+func (f *Font) ForEachWrappedLine(scale float32, maxWidth float32, fn func(index int, line string, y, width float32), fs string, argv ...interface{}) {
+
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	if len(indices) == 0 {
+		return
+	}
+
+	//lay the lines out first, for fn to be free to change the settings of the font
+	f.mu.RLock()
+	wrapped := f.wrapLines(scale, maxWidth, indices)
+	ys := make([]float32, len(wrapped))
+	widths := make([]float32, len(wrapped))
+	var x, y float32
+	for i, line := range wrapped {
+		ys[i], widths[i] = y, f.lineWidth(scale, line)
+		x, y = f.nextLine(x, y, scale)
+	}
+	f.mu.RUnlock()
+
+	for i, line := range wrapped {
+		fn(i, string(line), ys[i], widths[i])
+	}
+}
+
+// wrapLines splits text into lines no wider than maxWidth, breaking on newlines and spaces.
+// The space a line is broken on is dropped.
+func (f *Font) wrapLines(scale float32, maxWidth float32, text []rune) [][]rune {
+	var lines [][]rune
+
+	for _, hard := range splitLines(text) {
+		var line []rune
+		for i, word := range splitWords(hard) {
+			candidate := word
+			if i > 0 {
+				candidate = append(append(append([]rune(nil), line...), ' '), word...)
+			}
+			if f.lineWidth(scale, candidate) <= maxWidth {
+				line = candidate
+				continue
+			}
+
+			if i > 0 {
+				lines = append(lines, line)
+			}
+
+			// break words that do not fit on a line of their own
+			for len(word) > 1 && f.lineWidth(scale, word) > maxWidth {
+				n := f.fitRunes(scale, maxWidth, word)
+				lines = append(lines, word[:n])
+				word = word[n:]
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// fitRunes returns how many runes from the start of text fit in maxWidth, at least 1.
+func (f *Font) fitRunes(scale float32, maxWidth float32, text []rune) int {
+	n := 1
+	for n < len(text) && f.lineWidth(scale, text[:n+1]) <= maxWidth {
+		n++
+	}
+	return n
+}
+
+// lineWidth returns the width of a single line of text.
+func (f *Font) lineWidth(scale float32, line []rune) float32 {
+	width, _ := f.layout(scale, line, func(int, *character, *Font, float32, float32) {})
+	return width
+}
+
+// splitWords splits a line on spaces, without the spaces themselves.
+func splitWords(line []rune) [][]rune {
+	var words [][]rune
+	start := 0
+	for i, r := range line {
+		if r == ' ' {
+			words = append(words, line[start:i])
+			start = i + 1
+		}
+	}
+	return append(words, line[start:])
+}
+
+// PrintfClipped draws a string to the screen like Printf, truncating it with an ellipsis if it
+// is wider than maxWidth. The ellipsis is U+2026 if the font or one of its fallbacks has
+// it, "..." otherwise. Nothing is drawn when even the ellipsis is wider than maxWidth.
+// It returns the width of the text that was drawn.
+func (f *Font) PrintfClipped(x, y float32, scale float32, maxWidth float32, fs string, argv ...interface{}) (float32, error) {
+
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	if len(indices) == 0 {
+		return 0, nil
+	}
+
+	indices = f.truncate(scale, maxWidth, indices)
+
+	return f.lineWidth(scale, indices), f.draw(f.appendText(f.scratch(scale), x, y, scale, indices))
+}
+
+// truncate returns text unchanged if it fits in maxWidth, otherwise the longest
+// prefix of it followed by an ellipsis that does, or nothing if the ellipsis alone does not.
+func (f *Font) truncate(scale float32, maxWidth float32, text []rune) []rune {
+	if f.lineWidth(scale, text) <= maxWidth {
+		return text
+	}
+
+	ellipsis := []rune("…")
+	if _, _, ok := f.lookupGlyph(ellipsis[0]); !ok {
+		ellipsis = []rune("...")
+	}
+	if f.lineWidth(scale, ellipsis) > maxWidth {
+		return nil
+	}
+
+	n := len(text)
+	for n > 0 && f.lineWidth(scale, append(append([]rune(nil), text[:n]...), ellipsis...)) > maxWidth {
+		n--
+	}
+	return append(append([]rune(nil), text[:n]...), ellipsis...)
+}
+
+// PrintfClip draws a string to the screen like Printf, cut to the clip rectangle with the
+// scissor test, so that glyphs overflowing it are drawn in part. clip is in pixels of the
+// viewport, y-down from its top or y-up from its bottom like the text. The scissor state is
+// restored afterwards. Text batched with Begin before the call is drawn first, unclipped.
+func (f *Font) PrintfClip(clip Rect, x, y float32, scale float32, fs string, argv ...interface{}) error {
+
+	indices := []rune(fmt.Sprintf(fs, argv...))
+
+	if len(indices) == 0 {
+		return nil
+	}
+
+	f.flush()
+
+	//save the scissor state to put it back
+	enabled := gl.IsEnabled(gl.SCISSOR_TEST)
+	var box, viewport [4]int32
+	gl.GetIntegerv(gl.SCISSOR_BOX, &box[0])
+	gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+
+	//the scissor box is y-up from the bottom of the viewport
+	bottom := clip.Y
+	if f.yAxis == YDown {
+		bottom = float32(viewport[3]) - clip.Y - clip.H
+	}
+	gl.Enable(gl.SCISSOR_TEST)
+	gl.Scissor(viewport[0]+int32(clip.X), viewport[1]+int32(bottom), int32(clip.W), int32(clip.H))
+
+	err := f.render(f.appendText(f.scratch(scale), x, y, scale, indices))
+
+	gl.Scissor(box[0], box[1], box[2], box[3])
+	if !enabled {
+		gl.Disable(gl.SCISSOR_TEST)
+	}
+
+	return err
+}